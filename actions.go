@@ -304,6 +304,9 @@ func (action shellAction) Action(files []string, options map[string]interface{})
 
 func runCommand(cmdName string, args []string) {
 	cmd := exec.Command(cmdName, args...)
+	// Commands run in the directory of the configuration file, like the paths in it are resolved
+	cmd.Dir = configDir()
+	cmd.Env = configEnvironment()
 	if response, err := cmd.CombinedOutput(); err != nil {
 		errorMsg(fmt.Sprintf("Error running command '%s'\n%s", cmdName, response), err)
 	}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// archiveTime is the modification time of every archive entry so that archives of the same build are identical.
+// It can be set with the SOURCE_DATE_EPOCH environment variable.
+var archiveTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type archiveWriter interface {
+	addDir(name string) error
+	addFile(name string, size int64, r io.Reader) error
+	Close() error
+}
+
+// expandPlaceholders replaces the placeholders of an archive or manifest name
+func expandPlaceholders(name string) string {
+	name = strings.Replace(name, "{target}", config.Target, -1)
+	return strings.Replace(name, "{version}", config.Version, -1)
+}
+
+func createArchive(outputPath, prefix string) {
+	fmt.Printf("Creating archive...\n")
+	if config.Version == "" && (strings.Contains(argArchive, "{version}") || strings.Contains(argArchivePrefix, "{version}")) {
+		errorMsg(fmt.Sprintf("The archive uses '{version}' but no 'version' is defined in %s.", configFile), nil)
+		return
+	}
+
+	files, _, err := filesInPath(config.BuildDir)
+	if err != nil {
+		errorMsg(fmt.Sprintf("Folder '%s' not found.\n", config.BuildDir), err)
+		return
+	}
+
+	err = os.MkdirAll(filepath.Dir(outputPath), 0755)
+	if err != nil {
+		errorMsg(fmt.Sprintf("Could not create directory for archive '%s'", outputPath), err)
+		return
+	}
+	of, err := os.Create(outputPath)
+	if err != nil {
+		errorMsg(fmt.Sprintf("Could not create archive '%s'", outputPath), err)
+		return
+	}
+
+	w, err := newArchiveWriter(outputPath, of)
+	if err == nil {
+		err = writeArchive(w, files, strings.Trim(filepath.ToSlash(prefix), "/"))
+		if closeErr := w.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if closeErr := of.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		errorMsg(fmt.Sprintf("Could not create archive '%s'. Removing archive.", outputPath), err)
+		os.Remove(outputPath)
+		return
+	}
+	fmt.Printf("Created archive '%s'.\n\n", outputPath)
+}
+
+// writeArchive adds the files of the build directory and their directories to an archive in sorted order
+func writeArchive(w archiveWriter, files []string, prefix string) error {
+	entries := make(map[string]string)
+	dirs := make(map[string]bool)
+	var names []string
+	for _, file := range files {
+		name := strings.TrimPrefix(path.Join(prefix, strings.TrimPrefix(file, config.BuildDir)), "/")
+		entries[name] = file
+		names = append(names, name)
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+
+	var dirNames []string
+	for dir := range dirs {
+		dirNames = append(dirNames, dir)
+	}
+	sort.Strings(dirNames)
+	for _, dir := range dirNames {
+		if err := w.addDir(dir); err != nil {
+			return fmt.Errorf("could not add directory '%s' to archive: %s", dir, err)
+		}
+	}
+
+	sort.Strings(names)
+	for _, name := range names {
+		if err := addArchiveFile(w, name, entries[name]); err != nil {
+			return fmt.Errorf("could not add file '%s' to archive: %s", entries[name], err)
+		}
+	}
+	return nil
+}
+
+func addArchiveFile(w archiveWriter, name, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return w.addFile(name, info.Size(), f)
+}
+
+// newArchiveWriter returns a writer for the archive format matching the extension of the archive
+func newArchiveWriter(outputPath string, w io.Writer) (archiveWriter, error) {
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		seconds, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SOURCE_DATE_EPOCH '%s'", epoch)
+		}
+		archiveTime = time.Unix(seconds, 0).UTC()
+	}
+
+	name := strings.ToLower(outputPath)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		zw := zip.NewWriter(w)
+		zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, flate.BestCompression)
+		})
+		return &zipArchive{zw}, nil
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		gw, err := gzip.NewWriterLevel(w, gzip.BestCompression)
+		if err != nil {
+			return nil, err
+		}
+		gw.ModTime = archiveTime
+		return &tarArchive{tar.NewWriter(gw), gw}, nil
+	case strings.HasSuffix(name, ".tar.zst") || strings.HasSuffix(name, ".tzst"):
+		zw, err := zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedBestCompression), zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return &tarArchive{tar.NewWriter(zw), zw}, nil
+	}
+	return nil, fmt.Errorf("unsupported archive format, use .zip, .tar.gz or .tar.zst")
+}
+
+type zipArchive struct {
+	w *zip.Writer
+}
+
+func (a *zipArchive) addDir(name string) error {
+	header := &zip.FileHeader{Name: fmt.Sprintf("%s/", name), Method: zip.Store, Modified: archiveTime}
+	header.SetMode(os.ModeDir | 0755)
+	_, err := a.w.CreateHeader(header)
+	return err
+}
+
+func (a *zipArchive) addFile(name string, size int64, r io.Reader) error {
+	header := &zip.FileHeader{Name: name, Method: zip.Deflate, Modified: archiveTime}
+	header.SetMode(0644)
+	f, err := a.w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	return err
+}
+
+func (a *zipArchive) Close() error {
+	return a.w.Close()
+}
+
+// tarArchive writes a tar archive to a compressor that is closed along with it
+type tarArchive struct {
+	w          *tar.Writer
+	compressor io.WriteCloser
+}
+
+func (a *tarArchive) addDir(name string) error {
+	return a.w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     fmt.Sprintf("%s/", name),
+		Mode:     0755,
+		ModTime:  archiveTime,
+	})
+}
+
+func (a *tarArchive) addFile(name string, size int64, r io.Reader) error {
+	err := a.w.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0644,
+		ModTime:  archiveTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.CopyN(a.w, r, size)
+	return err
+}
+
+func (a *tarArchive) Close() error {
+	err := a.w.Close()
+	if compressErr := a.compressor.Close(); err == nil {
+		err = compressErr
+	}
+	return err
+}
@@ -5,6 +5,6 @@ import (
 )
 
 func configTemplate() ([]byte, error) {
-	b64 := "ewogICAgInRlbXBsYXRlVmVyc2lvbiI6IDEsCiAgICAic3JjRGlyIjogIi4vc3JjIiwKICAgICJidWlsZERpciI6ICIuL2J1aWxkIiwKICAgICJ0YXJnZXQiOiAiY29tbW9uIiwKICAgICJ0YXJnZXRzIjogewogICAgICAgICJjb21tb24iOiB7CiAgICAgICAgICAgICJkZXBlbmRlbmN5IjogbnVsbAogICAgICAgIH0KICAgIH0sCiAgICAidGFza3MiOiB7CiAgICAgICAgIlNjcmlwdHMiOiB7CiAgICAgICAgICAgICJnbG9icyI6IFsiLmpzIl0sCiAgICAgICAgICAgICJhY3Rpb25zIjogWwogICAgICAgICAgICAgICAgewogICAgICAgICAgICAgICAgICAgICJhY3Rpb24iOiAiY29uY2F0IiwKICAgICAgICAgICAgICAgICAgICAib3B0aW9ucyI6IHsKICAgICAgICAgICAgICAgICAgICAgICAgInNlcGFyYXRvciI6ICJcblxuLyotLS0tLS0tLS0tKi9cblxuIiwKICAgICAgICAgICAgICAgICAgICAgICAgIm91dHB1dCI6ICIvanMvYXBwLmNvbmNhdC5qcyIKICAgICAgICAgICAgICAgICAgICB9CiAgICAgICAgICAgICAgICB9LCAKICAgICAgICAgICAgICAgIHsKICAgICAgICAgICAgICAgICAgICAiYWN0aW9uIjogImpzLW1pbmlmeSIsCiAgICAgICAgICAgICAgICAgICAgIm9wdGlvbnMiOiB7CiAgICAgICAgICAgICAgICAgICAgICAgICJvdXRwdXQiOiAiL2pzL2FwcC5taW4uanMiCiAgICAgICAgICAgICAgICAgICAgfQogICAgICAgICAgICAgICAgfQogICAgICAgICAgICBdCiAgICAgICAgfSwKICAgICAgICAiU0FTUyI6IHsKICAgICAgICAgICAgImdsb2JzIjogWyIuc2NzcyJdLAogICAgICAgICAgICAiYWN0aW9ucyI6IFt7ImFjdGlvbiI6ICJzYXNzIn1dCiAgICAgICAgfSwKICAgICAgICAiVGVtcGxhdGVzIjogewogICAgICAgICAgICAiZ2xvYnMiOiBbIi5odG1sIl0sCiAgICAgICAgICAgICJhY3Rpb25zIjogW3siYWN0aW9uIjogImNvbGxhdGUifV0KICAgICAgICB9LAogICAgICAgICJJbWFnZXMiOiB7CiAgICAgICAgICAgICJnbG9icyI6IFsiKC5wbmd8LmpwZ3wuc3ZnKSJdLAogICAgICAgICAgICAiYWN0aW9ucyI6IFt7ImFjdGlvbiI6ICJjb2xsYXRlIn1dCiAgICAgICAgfQogICAgfQp9"
+	b64 := "ewogICAgIiRzY2hlbWEiOiAiaHR0cHM6Ly9yYXcuZ2l0aHVidXNlcmNvbnRlbnQuY29tL3J5YW5taXRjaGVuZXIvd2ViLWJ1aWxkL21hc3Rlci93ZWItYnVpbGQuc2NoZW1hLmpzb24iLAogICAgInRlbXBsYXRlVmVyc2lvbiI6IDEsCiAgICAic3JjRGlyIjogIi4vc3JjIiwKICAgICJidWlsZERpciI6ICIuL2J1aWxkIiwKICAgICJ0YXJnZXQiOiAiY29tbW9uIiwKICAgICJ0YXJnZXRzIjogewogICAgICAgICJjb21tb24iOiB7CiAgICAgICAgICAgICJkZXBlbmRlbmN5IjogbnVsbAogICAgICAgIH0KICAgIH0sCiAgICAidGFza3MiOiB7CiAgICAgICAgIlNjcmlwdHMiOiB7CiAgICAgICAgICAgICJnbG9icyI6IFsiLmpzIl0sCiAgICAgICAgICAgICJhY3Rpb25zIjogWwogICAgICAgICAgICAgICAgewogICAgICAgICAgICAgICAgICAgICJhY3Rpb24iOiAiY29uY2F0IiwKICAgICAgICAgICAgICAgICAgICAib3B0aW9ucyI6IHsKICAgICAgICAgICAgICAgICAgICAgICAgInNlcGFyYXRvciI6ICJcblxuLyotLS0tLS0tLS0tKi9cblxuIiwKICAgICAgICAgICAgICAgICAgICAgICAgIm91dHB1dCI6ICIvanMvYXBwLmNvbmNhdC5qcyIKICAgICAgICAgICAgICAgICAgICB9CiAgICAgICAgICAgICAgICB9LCAKICAgICAgICAgICAgICAgIHsKICAgICAgICAgICAgICAgICAgICAiYWN0aW9uIjogImpzLW1pbmlmeSIsCiAgICAgICAgICAgICAgICAgICAgIm9wdGlvbnMiOiB7CiAgICAgICAgICAgICAgICAgICAgICAgICJvdXRwdXQiOiAiL2pzL2FwcC5taW4uanMiCiAgICAgICAgICAgICAgICAgICAgfQogICAgICAgICAgICAgICAgfQogICAgICAgICAgICBdCiAgICAgICAgfSwKICAgICAgICAiU0FTUyI6IHsKICAgICAgICAgICAgImdsb2JzIjogWyIuc2NzcyJdLAogICAgICAgICAgICAiYWN0aW9ucyI6IFt7ImFjdGlvbiI6ICJzYXNzIn1dCiAgICAgICAgfSwKICAgICAgICAiVGVtcGxhdGVzIjogewogICAgICAgICAgICAiZ2xvYnMiOiBbIi5odG1sIl0sCiAgICAgICAgICAgICJhY3Rpb25zIjogW3siYWN0aW9uIjogImNvbGxhdGUifV0KICAgICAgICB9LAogICAgICAgICJJbWFnZXMiOiB7CiAgICAgICAgICAgICJnbG9icyI6IFsiKC5wbmd8LmpwZ3wuc3ZnKSJdLAogICAgICAgICAgICAiYWN0aW9ucyI6IFt7ImFjdGlvbiI6ICJjb2xsYXRlIn1dCiAgICAgICAgfQogICAgfQp9"
 	return base64.StdEncoding.DecodeString(b64)
 }
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// browserReleases lists the recent major releases of each browser, oldest first. It is used to resolve
+// "last N versions" queries and should be updated from time to time.
+var browserReleases = map[string][]float64{
+	"chrome":  {109, 120, 121, 122, 123, 124, 125, 126, 127, 128, 129, 130, 131, 132, 133, 134, 135, 136, 137, 138, 139, 140, 141},
+	"edge":    {109, 120, 121, 122, 123, 124, 125, 126, 127, 128, 129, 130, 131, 132, 133, 134, 135, 136, 137, 138, 139, 140, 141},
+	"firefox": {115, 120, 121, 122, 123, 124, 125, 126, 127, 128, 129, 130, 131, 132, 133, 134, 135, 136, 137, 138, 139, 140, 141, 142, 143},
+	"safari":  {14, 14.1, 15, 15.4, 15.6, 16, 16.4, 16.6, 17, 17.4, 17.6, 18, 18.4, 18.6, 26},
+	"ios_saf": {14, 14.5, 15, 15.4, 15.6, 16, 16.4, 16.6, 17, 17.4, 17.6, 18, 18.4, 18.6, 26},
+	"opera":   {95, 100, 105, 106, 107, 108, 109, 110, 111, 112, 113, 114, 115, 116, 117, 118, 119, 120, 121},
+	"samsung": {20, 21, 22, 23, 24, 25, 26, 27, 28},
+	"ie":      {6, 7, 8, 9, 10, 11},
+}
+
+// deadBrowsers are browsers without official support or updates
+var deadBrowsers = []string{"ie"}
+
+var browserAliases = map[string]string{
+	"explorer":         "ie",
+	"internetexplorer": "ie",
+	"ios":              "ios_saf",
+	"iossafari":        "ios_saf",
+	"ff":               "firefox",
+	"samsunginternet":  "samsung",
+}
+
+var browserVersionQueryRegex = regexp.MustCompile(`^([a-z_ ]+?)\s*(>=|<=|>|<)?\s*([0-9.]+)$`)
+var lastVersionsQueryRegex = regexp.MustCompile(`^last\s+(\d+)\s+(?:([a-z_ ]+?)\s+)?versions?$`)
+
+// browserTargets maps browsers to the oldest version that is targeted
+type browserTargets map[string]float64
+
+// parseBrowserQuery resolves a browserslist-style query into the oldest targeted version of each browser.
+// Usage based queries (i.e. "> 1%") are not supported as no usage data is available.
+func parseBrowserQuery(queries []string) (browserTargets, error) {
+	versions := make(map[string][]float64)
+
+	var expanded []string
+	for _, query := range queries {
+		for _, part := range strings.Split(query, ",") {
+			expanded = append(expanded, strings.ToLower(strings.TrimSpace(part)))
+		}
+	}
+
+	for _, query := range expanded {
+		if query == "" {
+			continue
+		} else if query == "defaults" {
+			for _, defaultQuery := range []string{"last 2 versions", "firefox esr", "not dead"} {
+				if err := applyBrowserQuery(versions, defaultQuery); err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+		if err := applyBrowserQuery(versions, query); err != nil {
+			return nil, err
+		}
+	}
+
+	targets := make(browserTargets)
+	for browser, list := range versions {
+		if len(list) == 0 {
+			continue
+		}
+		sort.Float64s(list)
+		targets[browser] = list[0]
+	}
+	return targets, nil
+}
+
+func applyBrowserQuery(versions map[string][]float64, query string) error {
+	if strings.HasPrefix(query, "not ") {
+		excluded := make(map[string][]float64)
+		if err := applyBrowserQuery(excluded, strings.TrimPrefix(query, "not ")); err != nil {
+			return err
+		}
+		for browser, list := range excluded {
+			for _, version := range list {
+				versions[browser] = removeVersion(versions[browser], version)
+			}
+		}
+		return nil
+	}
+
+	switch {
+	case query == "dead":
+		for _, browser := range deadBrowsers {
+			versions[browser] = append(versions[browser], browserReleases[browser]...)
+		}
+	case query == "firefox esr":
+		versions["firefox"] = append(versions["firefox"], 115, 128, 140)
+	case lastVersionsQueryRegex.MatchString(query):
+		matches := lastVersionsQueryRegex.FindStringSubmatch(query)
+		count, _ := strconv.Atoi(matches[1])
+		browsers := make([]string, 0, len(browserReleases))
+		if matches[2] != "" {
+			browser, err := browserName(matches[2])
+			if err != nil {
+				return err
+			}
+			browsers = append(browsers, browser)
+		} else {
+			for browser := range browserReleases {
+				browsers = append(browsers, browser)
+			}
+		}
+		for _, browser := range browsers {
+			releases := browserReleases[browser]
+			versions[browser] = append(versions[browser], releases[maxInt(0, len(releases)-count):]...)
+		}
+	case browserVersionQueryRegex.MatchString(query):
+		matches := browserVersionQueryRegex.FindStringSubmatch(query)
+		browser, err := browserName(matches[1])
+		if err != nil {
+			return err
+		}
+		version, err := strconv.ParseFloat(matches[3], 64)
+		if err != nil {
+			return fmt.Errorf("invalid version in browser query '%s'", query)
+		}
+		versions[browser] = append(versions[browser], browserVersionRange(browser, matches[2], version)...)
+	default:
+		return fmt.Errorf("unsupported browser query '%s'", query)
+	}
+	return nil
+}
+
+func browserVersionRange(browser, operator string, version float64) []float64 {
+	if operator == "" {
+		return []float64{version}
+	}
+
+	var versions []float64
+	for _, release := range browserReleases[browser] {
+		if (operator == ">=" && release >= version) || (operator == ">" && release > version) ||
+			(operator == "<=" && release <= version) || (operator == "<" && release < version) {
+			versions = append(versions, release)
+		}
+	}
+	// Include the exact version for lower bounds older than the release list
+	if operator == ">=" {
+		versions = append(versions, version)
+	}
+	return versions
+}
+
+func browserName(name string) (string, error) {
+	name = strings.Replace(strings.TrimSpace(name), " ", "", -1)
+	if alias, ok := browserAliases[name]; ok {
+		name = alias
+	}
+	if _, ok := browserReleases[name]; !ok {
+		return "", fmt.Errorf("unknown browser '%s'", name)
+	}
+	return name, nil
+}
+
+func removeVersion(versions []float64, version float64) []float64 {
+	for i := 0; i < len(versions); i++ {
+		if versions[i] == version {
+			versions = append(versions[:i], versions[i+1:]...)
+			i--
+		}
+	}
+	return versions
+}
+
+// olderThan reports whether any targeted browser is older than the version listed for it. Browsers that are
+// not listed are considered supported.
+func (targets browserTargets) olderThan(support map[string]float64) bool {
+	for browser, version := range targets {
+		supported, ok := support[browser]
+		if ok && version < supported {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/evanw/esbuild/pkg/api"
+)
+
+type bundleAction struct{}
+
+var bundleFormats = map[string]api.Format{
+	"iife": api.FormatIIFE,
+	"esm":  api.FormatESModule,
+	"cjs":  api.FormatCommonJS,
+}
+
+var bundleSourceMaps = map[string]api.SourceMap{
+	"linked":   api.SourceMapLinked,
+	"inline":   api.SourceMapInline,
+	"external": api.SourceMapExternal,
+	"both":     api.SourceMapInlineAndExternal,
+}
+
+var bundleJSX = map[string]api.JSX{
+	"transform": api.JSXTransform,
+	"preserve":  api.JSXPreserve,
+	"automatic": api.JSXAutomatic,
+}
+
+var bundleTargets = map[string]api.Target{
+	"esnext": api.ESNext,
+	"es5":    api.ES5,
+	"es2015": api.ES2015,
+	"es2016": api.ES2016,
+	"es2017": api.ES2017,
+	"es2018": api.ES2018,
+	"es2019": api.ES2019,
+	"es2020": api.ES2020,
+	"es2021": api.ES2021,
+	"es2022": api.ES2022,
+}
+
+// bundleExtensions are tried in order when resolving an import without an extension
+var bundleExtensions = []string{"", ".ts", ".tsx", ".js", ".jsx", ".mjs", ".json", "/index.ts", "/index.tsx", "/index.js", "/index.jsx"}
+
+func (action bundleAction) Action(files []string, options map[string]interface{}) (outputFiles []string) {
+	if len(files) == 0 {
+		return files
+	}
+
+	buildOptions := api.BuildOptions{
+		Bundle:         true,
+		Write:          true,
+		AllowOverwrite: true,
+		LogLevel:       api.LogLevelSilent,
+		Platform:       api.PlatformBrowser,
+		GlobalName:     optionString(options, "globalName", ""),
+		JSXFactory:     optionString(options, "jsxFactory", ""),
+		JSXFragment:    optionString(options, "jsxFragment", ""),
+		External:       optionStrings(options, "external"),
+		Splitting:      optionBool(options, "splitting", false),
+		Plugins:        []api.Plugin{targetResolvePlugin()},
+	}
+
+	format, ok := bundleFormats[optionString(options, "format", "iife")]
+	if !ok {
+		errorMsg(fmt.Sprintf("Invalid 'format' option '%s' in 'bundle' action. Skipping task...", options["format"]), nil)
+		return files
+	}
+	buildOptions.Format = format
+
+	if minify := optionBool(options, "minify", false); minify {
+		buildOptions.MinifyWhitespace = true
+		buildOptions.MinifyIdentifiers = true
+		buildOptions.MinifySyntax = true
+	}
+
+	switch sourceMap := options["sourcemap"].(type) {
+	case bool:
+		if sourceMap {
+			buildOptions.Sourcemap = api.SourceMapLinked
+		}
+	case string:
+		if buildOptions.Sourcemap, ok = bundleSourceMaps[sourceMap]; !ok {
+			errorMsg(fmt.Sprintf("Invalid 'sourcemap' option '%s' in 'bundle' action. Skipping task...", sourceMap), nil)
+			return files
+		}
+	}
+
+	if jsx, ok := options["jsx"].(string); ok {
+		if buildOptions.JSX, ok = bundleJSX[jsx]; !ok {
+			errorMsg(fmt.Sprintf("Invalid 'jsx' option '%s' in 'bundle' action. Skipping task...", jsx), nil)
+			return files
+		}
+	}
+
+	if target, ok := options["target"].(string); ok {
+		if buildOptions.Target, ok = bundleTargets[strings.ToLower(target)]; !ok {
+			errorMsg(fmt.Sprintf("Invalid 'target' option '%s' in 'bundle' action. Skipping task...", target), nil)
+			return files
+		}
+	}
+
+	if define, ok := options["define"].(map[string]interface{}); ok {
+		buildOptions.Define = make(map[string]string)
+		for key, value := range define {
+			if str, ok := value.(string); ok {
+				buildOptions.Define[key] = str
+				continue
+			}
+			// Non-string values are passed as their JavaScript literal
+			data, _ := json.Marshal(value)
+			buildOptions.Define[key] = string(data)
+		}
+	}
+
+	if outputFile, ok := options["output"].(string); ok {
+		if len(files) > 1 {
+			errorMsg("The 'output' option in 'bundle' action requires a single entry point. Skipping task...", nil)
+			return files
+		}
+		buildOptions.Outfile = fmt.Sprintf("%s%s", config.BuildDir, outputFile)
+		buildOptions.EntryPoints = files
+	} else {
+		buildOptions.Outdir = fmt.Sprintf("%s%s", config.BuildDir, strings.TrimSuffix(optionString(options, "outdir", ""), "/"))
+		for _, file := range files {
+			outputPath := strings.TrimPrefix(relativePath(file), "/")
+			outputPath = outputPath[:len(outputPath)-len(filepath.Ext(outputPath))]
+			buildOptions.EntryPointsAdvanced = append(buildOptions.EntryPointsAdvanced, api.EntryPoint{InputPath: file, OutputPath: outputPath})
+		}
+	}
+
+	result := api.Build(buildOptions)
+	for _, message := range result.Errors {
+		if message.Location != nil {
+			errorMsg(fmt.Sprintf("Could not bundle '%s' (line %d, column %d).", message.Location.File, message.Location.Line, message.Location.Column), fmt.Errorf("%s", message.Text))
+		} else {
+			errorMsg("Could not bundle files in 'bundle' action.", fmt.Errorf("%s", message.Text))
+		}
+	}
+	if len(result.Errors) > 0 {
+		return files
+	}
+
+	for _, outputFile := range result.OutputFiles {
+		outputFiles = append(outputFiles, filepath.ToSlash(outputFile.Path))
+	}
+	return outputFiles
+}
+
+// targetResolvePlugin resolves relative imports through the target dependency tree so that a module in a child
+// target replaces the module with the same path in its parent targets
+func targetResolvePlugin() api.Plugin {
+	return api.Plugin{
+		Name: "web-build-targets",
+		Setup: func(build api.PluginBuild) {
+			build.OnResolve(api.OnResolveOptions{Filter: `^\.\.?(/|$)`}, func(args api.OnResolveArgs) (api.OnResolveResult, error) {
+				importer := filepath.ToSlash(args.Importer)
+				if !strings.HasPrefix(importer, config.SrcDir) {
+					return api.OnResolveResult{}, nil
+				}
+
+				relative := path.Join(path.Dir(relativePath(importer)), args.Path)
+				for _, ext := range bundleExtensions {
+					if file, _, ok := resolveTargetPath(fmt.Sprintf("%s%s", relative, ext)); ok {
+						return api.OnResolveResult{Path: filepath.FromSlash(file)}, nil
+					}
+				}
+				return api.OnResolveResult{}, nil
+			})
+		},
+	}
+}
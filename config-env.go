@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// configEnv holds the variables loaded from the .env files and the profile of the configuration. Variables of the
+// environment take precedence over them.
+var configEnv map[string]string
+
+// interpolateConfig loads the .env files for the target and the variables of the selected profile and replaces the
+// variables in every string of the configuration. If target is empty, the target in the configuration is used.
+func interpolateConfig(data map[string]interface{}, target string) (map[string]interface{}, error) {
+	configEnv = make(map[string]string)
+	if err := loadEnvFile(filepath.Join(configDir(), ".env")); err != nil {
+		return nil, err
+	}
+
+	if target == "" {
+		if value, ok := data["target"].(string); ok {
+			var err error
+			if target, err = interpolate(value); err != nil {
+				return nil, fmt.Errorf("%s: target: %s", configFile, err)
+			}
+		}
+	}
+	if target != "" {
+		if err := loadEnvFile(filepath.Join(configDir(), fmt.Sprintf(".env.%s", target))); err != nil {
+			return nil, err
+		}
+	}
+
+	// Variables of the profile take precedence over the .env files
+	variables, err := profileVariables(data, selectedProfile(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", configFile, err)
+	}
+	for name, value := range variables {
+		interpolated, err := interpolate(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: profile variable '%s': %s", configFile, name, err)
+		}
+		configEnv[name] = interpolated
+	}
+
+	interpolated, err := interpolateConfigValue(data, "")
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", configFile, err)
+	}
+	return interpolated.(map[string]interface{}), nil
+}
+
+// interpolateConfigValue replaces the variables in the strings of a configuration value. path is the location of
+// the value for errors, i.e. 'tasks.Scripts.actions[0].options.output'.
+func interpolateConfigValue(value interface{}, path string) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		interpolated, err := interpolate(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", path, err)
+		}
+		return interpolated, nil
+	case map[string]interface{}:
+		object := make(map[string]interface{})
+		for key, item := range v {
+			interpolated, err := interpolateConfigValue(item, configPath(path, key))
+			if err != nil {
+				return nil, err
+			}
+			object[key] = interpolated
+		}
+		return object, nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			interpolated, err := interpolateConfigValue(item, fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = interpolated
+		}
+		return list, nil
+	}
+	return value, nil
+}
+
+// interpolate replaces ${VAR} with the value of the variable VAR and ${VAR:-default} with the value of VAR, or
+// default if VAR is unset or empty. $${ is replaced with a literal ${.
+func interpolate(s string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if strings.HasPrefix(s[i:], "$${") {
+			out.WriteString("${")
+			i += 2
+			continue
+		} else if !strings.HasPrefix(s[i:], "${") {
+			out.WriteByte(s[i])
+			continue
+		}
+
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable in '%s'", s)
+		}
+		expression := s[i+2 : i+end]
+		name, fallback, hasFallback := expression, "", false
+		if index := strings.Index(expression, ":-"); index > -1 {
+			name, fallback, hasFallback = expression[:index], expression[index+2:], true
+		}
+		if !isEnvName(name) {
+			return "", fmt.Errorf("invalid variable '${%s}'", expression)
+		}
+
+		value, ok := lookupConfigEnv(name)
+		switch {
+		case hasFallback && value == "":
+			out.WriteString(fallback)
+		case !ok:
+			return "", fmt.Errorf("variable '%s' is not set. Use '${%s:-}' to allow it to be empty", name, name)
+		default:
+			out.WriteString(value)
+		}
+		i += end
+	}
+	return out.String(), nil
+}
+
+func isEnvName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, c := range name {
+		if c != '_' && (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// lookupConfigEnv returns the value of a variable from the environment or the .env files
+func lookupConfigEnv(name string) (string, bool) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true
+	}
+	value, ok := configEnv[name]
+	return value, ok
+}
+
+// loadEnvFile loads the variables of a .env file into configEnv, overriding variables of files loaded before it.
+// Missing files are ignored. Values may be quoted with double quotes, which support escapes and variables, or
+// single quotes, which are taken literally.
+func loadEnvFile(file string) error {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		index := strings.IndexByte(line, '=')
+		name := ""
+		if index > -1 {
+			name = strings.TrimSpace(strings.TrimPrefix(line[:index], "export "))
+		}
+		if !isEnvName(name) {
+			return fmt.Errorf("%s:%d: invalid variable definition '%s'", file, number, line)
+		}
+
+		value := strings.TrimSpace(line[index+1:])
+		if len(value) > 1 && value[0] == '\'' && strings.LastIndexByte(value, '\'') > 0 {
+			configEnv[name] = value[1:strings.LastIndexByte(value, '\'')]
+			continue
+		} else if len(value) > 1 && value[0] == '"' && strings.LastIndexByte(value, '"') > 0 {
+			value = value[1:strings.LastIndexByte(value, '"')]
+			value = strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\"`, `"`, `\\`, `\`).Replace(value)
+		} else if comment := strings.Index(value, " #"); comment > -1 {
+			value = strings.TrimSpace(value[:comment])
+		}
+		if value, err = interpolate(value); err != nil {
+			return fmt.Errorf("%s:%d: %s", file, number, err)
+		}
+		configEnv[name] = value
+	}
+	return scanner.Err()
+}
+
+// configEnvironment returns the environment for commands: the environment of web-build with the variables of the
+// .env files that it does not set
+func configEnvironment() []string {
+	environment := os.Environ()
+	var names []string
+	for name := range configEnv {
+		if _, ok := os.LookupEnv(name); !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		environment = append(environment, fmt.Sprintf("%s=%s", name, configEnv[name]))
+	}
+	return environment
+}
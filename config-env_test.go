@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	configEnv = map[string]string{"NAME": "web", "EMPTY": "", "WEB_BUILD_TEST": "file"}
+	t.Setenv("WEB_BUILD_TEST", "environment")
+
+	tests := []struct {
+		value  string
+		result string
+		err    string
+	}{
+		{"plain", "plain", ""},
+		{"costs $5", "costs $5", ""},
+		{"${NAME}", "web", ""},
+		{"a-${NAME}-${NAME}-b", "a-web-web-b", ""},
+		{"$${NAME}", "${NAME}", ""},
+		{"$${NAME} ${NAME}", "${NAME} web", ""},
+		{"${EMPTY}", "", ""},
+		{"${EMPTY:-}", "", ""},
+		{"${MISSING:-}", "", ""},
+		{"${MISSING:-fallback}", "fallback", ""},
+		{"${EMPTY:-fallback}", "fallback", ""},
+		{"${NAME:-fallback}", "web", ""},
+		{"${WEB_BUILD_TEST}", "environment", ""},
+		{"${MISSING}", "", "variable 'MISSING' is not set. Use '${MISSING:-}' to allow it to be empty"},
+		{"${NAME", "", "unterminated variable in '${NAME'"},
+		{"${1NAME}", "", "invalid variable '${1NAME}'"},
+		{"${}", "", "invalid variable '${}'"},
+	}
+	for _, test := range tests {
+		result, err := interpolate(test.value)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("interpolate(%q) error = %v, want %q", test.value, err, test.err)
+			}
+		} else if err != nil || result != test.result {
+			t.Errorf("interpolate(%q) = %q, %v, want %q", test.value, result, err, test.result)
+		}
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		variables map[string]string
+		err       string
+	}{
+		{"comments and blank lines", "# comment\n\n  # indented comment\nA=1\n", map[string]string{"A": "1"}, ""},
+		{"export prefix", "export A=1", map[string]string{"A": "1"}, ""},
+		{"unquoted values", "A = two words # comment\nB=a#b\nC=", map[string]string{"A": "two words", "B": "a#b", "C": ""}, ""},
+		{"double quotes", `A="quoted # not a comment\tand\n\"escapes\""`, map[string]string{"A": "quoted # not a comment\tand\n\"escapes\""}, ""},
+		{"single quotes", `A='literal ${B} \n'`, map[string]string{"A": `literal ${B} \n`}, ""},
+		{"variables", "A=1\nB=\"${A}-2\"\nC=${B}-3\nD=$${A}", map[string]string{"A": "1", "B": "1-2", "C": "1-2-3", "D": "${A}"}, ""},
+		{"later values override", "A=1\nA=2", map[string]string{"A": "2"}, ""},
+		{"invalid name", "A=1\n1A=2", nil, "%s:2: invalid variable definition '1A=2'"},
+		{"missing equals sign", "A", nil, "%s:1: invalid variable definition 'A'"},
+		{"unset variable", "\nA=${WEB_BUILD_MISSING}", nil, "%s:2: variable 'WEB_BUILD_MISSING' is not set. Use '${WEB_BUILD_MISSING:-}' to allow it to be empty"},
+	}
+	for _, test := range tests {
+		file := filepath.Join(t.TempDir(), ".env")
+		if err := ioutil.WriteFile(file, []byte(test.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		configEnv = make(map[string]string)
+		err := loadEnvFile(file)
+		if test.err != "" {
+			if want := fmt.Sprintf(test.err, file); err == nil || err.Error() != want {
+				t.Errorf("%s: loadEnvFile() error = %v, want %q", test.name, err, want)
+			}
+		} else if err != nil || !reflect.DeepEqual(configEnv, test.variables) {
+			t.Errorf("%s: loadEnvFile() = %v, %v, want %v", test.name, configEnv, err, test.variables)
+		}
+	}
+
+	configEnv = make(map[string]string)
+	if err := loadEnvFile(filepath.Join(t.TempDir(), ".env")); err != nil || len(configEnv) != 0 {
+		t.Errorf("loadEnvFile() of a missing file = %v, %v, want no variables", configEnv, err)
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// configFiles are the configuration files the configuration was resolved from, including the files it extends
+// and includes
+var configFiles []string
+
+// resolveConfig finds the configuration file and returns its content merged with the files it extends and includes
+func resolveConfig() (map[string]interface{}, error) {
+	var err error
+	configFile, err = findConfigFile()
+	if err != nil {
+		return nil, err
+	}
+	configFiles = nil
+	data, err := readConfigData(configFile, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files that are valid on their own may still be invalid once they are merged, i.e. if one file sets the
+	// action of another to one with different options. These problems have no position in a single file.
+	if problems := validateConfigData(data); len(problems) > 0 {
+		return nil, configValidationError(configFile, nil, problems)
+	}
+	return data, nil
+}
+
+// readConfigData reads a configuration file and merges it over the files it extends and includes. Files listed in
+// 'extends' are merged first, followed by the files listed in 'include' and finally the file itself. Objects are
+// merged key by key, any other value replaces the value it overrides and null removes it.
+func readConfigData(file string, chain []string) (map[string]interface{}, error) {
+	absolute, _ := filepath.Abs(file)
+	if stringInSlice(absolute, chain) {
+		return nil, fmt.Errorf("circular configuration '%s'", strings.Join(append(chain, absolute), "' -> '"))
+	}
+	chain = append(chain, absolute)
+	if !stringInSlice(absolute, configFiles) {
+		configFiles = append(configFiles, absolute)
+	}
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	jsonContent, err := configToJSON(file, content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", file, err)
+	}
+
+	var data map[string]interface{}
+	if err = json.Unmarshal(jsonContent, &data); err != nil {
+		return nil, configDecodeError(file, content, err)
+	}
+	if data == nil {
+		data = make(map[string]interface{})
+	}
+
+	// Every file is validated on its own so problems are reported with their position in the file
+	if problems := validateConfigData(data); len(problems) > 0 {
+		return nil, configValidationError(file, content, problems)
+	}
+	var partial Config
+	if err = json.Unmarshal(jsonContent, &partial); err != nil {
+		return nil, configDecodeError(file, content, err)
+	}
+	data = foldConfigKeys(data, reflect.TypeOf(partial)).(map[string]interface{})
+
+	extends, err := configReferences(file, data, "extends", false)
+	if err != nil {
+		return nil, err
+	}
+	includes, err := configReferences(file, data, "include", true)
+	if err != nil {
+		return nil, err
+	}
+	delete(data, "extends")
+	delete(data, "include")
+
+	merged := make(map[string]interface{})
+	for _, reference := range append(extends, includes...) {
+		referenced, err := readConfigData(reference, chain)
+		if err != nil {
+			return nil, err
+		}
+		mergeConfigData(merged, referenced)
+	}
+	mergeConfigData(merged, data)
+	return merged, nil
+}
+
+// configReferences returns the paths of the files listed under key, which may be a path or a list of paths
+// relative to the file. Globs are expanded if expandGlobs is set.
+func configReferences(file string, data map[string]interface{}, key string, expandGlobs bool) ([]string, error) {
+	var paths []string
+	switch value := data[key].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		paths = []string{value}
+	case []interface{}:
+		for _, item := range value {
+			path, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: '%s' must be a path or a list of paths", file, key)
+			}
+			paths = append(paths, path)
+		}
+	default:
+		return nil, fmt.Errorf("%s: '%s' must be a path or a list of paths", file, key)
+	}
+
+	var references []string
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(file), filepath.FromSlash(path))
+		}
+		if !expandGlobs {
+			references = append(references, path)
+			continue
+		}
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid '%s' pattern '%s'", file, key, path)
+		} else if len(matches) == 0 {
+			return nil, fmt.Errorf("%s: no files match '%s' in '%s'", file, path, key)
+		}
+		sort.Strings(matches)
+		references = append(references, matches...)
+	}
+	return references, nil
+}
+
+// mergeConfigData merges override into base. Objects are merged recursively, other values replace the values in
+// base and null values remove them. Keys are compared case-sensitively, so the keys of configuration files are
+// folded with foldConfigKeys first.
+func mergeConfigData(base, override map[string]interface{}) {
+	mergeConfigPath(base, override, nil)
+}
+
+// mergeConfigPath merges override into base like mergeConfigData. path is the location of base in the
+// configuration. The null values of the options of profiles are kept, so they remove the options of actions when the
+// profile is applied.
+func mergeConfigPath(base, override map[string]interface{}, path []string) {
+	for key, value := range override {
+		keyPath := append(append([]string{}, path...), key)
+		if value == nil {
+			if len(keyPath) > 4 && keyPath[0] == "profiles" && keyPath[2] == "options" {
+				base[key] = nil
+			} else {
+				delete(base, key)
+			}
+			continue
+		}
+		baseObject, baseIsObject := base[key].(map[string]interface{})
+		overrideObject, overrideIsObject := value.(map[string]interface{})
+		if baseIsObject && overrideIsObject {
+			mergeConfigPath(baseObject, overrideObject, keyPath)
+		} else if overrideIsObject {
+			object := make(map[string]interface{})
+			mergeConfigPath(object, overrideObject, keyPath)
+			base[key] = object
+		} else {
+			base[key] = value
+		}
+	}
+}
+
+// foldConfigKeys renames the keys of configuration data that match a field of t case-insensitively to the name of
+// the field (i.e. 'Tasks' to 'tasks'), so they are merged with the keys of other files the way they are decoded
+func foldConfigKeys(value interface{}, t reflect.Type) interface{} {
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		var keys []string
+		for key := range object {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		folded := make(map[string]interface{})
+		for _, key := range keys {
+			folded[key] = object[key]
+			for i := 0; i < t.NumField(); i++ {
+				if field := t.Field(i); strings.EqualFold(field.Name, key) {
+					delete(folded, key)
+					folded[lowerFirst(field.Name)] = foldConfigKeys(object[key], field.Type)
+					break
+				}
+			}
+		}
+		return folded
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return value
+		}
+		folded := make(map[string]interface{})
+		for key, item := range object {
+			folded[key] = foldConfigKeys(item, t.Elem())
+		}
+		return folded
+	case reflect.Slice:
+		list, ok := value.([]interface{})
+		if !ok {
+			return value
+		}
+		folded := make([]interface{}, len(list))
+		for i, item := range list {
+			folded[i] = foldConfigKeys(item, t.Elem())
+		}
+		return folded
+	}
+	return value
+}
+
+// configDecodeError adds the file and, for JSON files, the line and column to an error decoding the converted
+// content of the file
+func configDecodeError(file string, content []byte, err error) error {
+	if line, column, ok := configErrorPosition(content, err); ok && isJSONConfig(file) {
+		return fmt.Errorf("%s:%d:%d: %s", file, line, column, err)
+	}
+	return fmt.Errorf("%s: %s", file, err)
+}
+
+// configValidationError lists the problems of a configuration file with their positions. Without content, the
+// problems are listed without positions.
+func configValidationError(file string, content []byte, problems []schemaProblem) error {
+	positions := configPositions(file, content)
+	position := func(path string) (configPosition, bool) {
+		// Missing properties are reported at the closest parent with a position
+		for ; path != ""; path = parentConfigPath(path) {
+			if p, ok := positions[path]; ok {
+				return p, true
+			}
+		}
+		return configPosition{}, false
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, _ := position(problems[i].path)
+		b, _ := position(problems[j].path)
+		return a.line < b.line || (a.line == b.line && a.column < b.column)
+	})
+
+	var lines []string
+	for _, problem := range problems {
+		location := file
+		if p, ok := position(problem.path); ok {
+			location = fmt.Sprintf("%s:%d:%d", file, p.line, p.column)
+		}
+		if problem.path == "" {
+			lines = append(lines, fmt.Sprintf("%s: %s", location, problem.message))
+		} else {
+			lines = append(lines, fmt.Sprintf("%s: %s: %s", location, problem.path, problem.message))
+		}
+	}
+	return fmt.Errorf("%s", strings.Join(lines, "\n     "))
+}
+
+// parentConfigPath returns the path of the object or list containing path
+func parentConfigPath(path string) string {
+	index := strings.LastIndexAny(path, ".[")
+	if index < 0 {
+		return ""
+	}
+	return path[:index]
+}
+
+// printConfig prints the configuration resolved from the files it extends and includes, with its variables
+// replaced for the target given with -target
+func printConfig() {
+	target := argTarget
+	if strings.Contains(target, ",") {
+		target = ""
+	}
+	data, err := resolveConfig()
+	if err == nil {
+		data, err = interpolateConfig(data, strings.TrimSpace(target))
+	}
+	if err != nil {
+		errorMsg("Could not parse configuration file.", err)
+		os.Exit(1)
+	}
+	content, err := json.MarshalIndent(data, "", "    ")
+	if err != nil {
+		errorMsg("Could not print configuration.", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s\n", content)
+}
+
+// presetTemplate returns a configuration that extends a preset along with the source directories of the targets
+// of the preset
+func presetTemplate(preset string) ([]byte, []string, error) {
+	data, err := readConfigData(preset, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	srcDir, ok := data["srcDir"].(string)
+	if !ok {
+		srcDir = "./src"
+	}
+	dirs := []string{srcDir}
+	if targets, ok := data["targets"].(map[string]interface{}); ok && len(targets) > 0 {
+		dirs = nil
+		for target := range targets {
+			dirs = append(dirs, fmt.Sprintf("%s/%s", strings.TrimSuffix(srcDir, "/"), target))
+		}
+		sort.Strings(dirs)
+	}
+
+	content, err := json.MarshalIndent(map[string]interface{}{"$schema": schemaID, "extends": filepath.ToSlash(preset)}, "", "    ")
+	if err != nil {
+		return nil, nil, err
+	}
+	return append(content, '\n'), dirs, nil
+}
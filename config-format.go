@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configFileNames are the names of configuration files searched for, in order of precedence
+var configFileNames = []string{"web-build.json", "web-build.jsonc", "web-build.json5", "web-build.yaml", "web-build.yml", "web-build.toml"}
+
+// findConfigFile returns the configuration file given with -config or the first configuration file found in the
+// current directory or its parent directories
+func findConfigFile() (string, error) {
+	if argConfig != "" {
+		if _, err := os.Stat(argConfig); err != nil {
+			return "", err
+		}
+		return argConfig, nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for dir := cwd; ; dir = filepath.Dir(dir) {
+		for _, name := range configFileNames {
+			file := filepath.Join(dir, name)
+			if info, err := os.Stat(file); err == nil && !info.IsDir() {
+				if relative, err := filepath.Rel(cwd, file); err == nil {
+					return relative, nil
+				}
+				return file, nil
+			}
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+	}
+	return "", fmt.Errorf("no configuration file (%s) found in '%s' or its parent directories", strings.Join(configFileNames, ", "), cwd)
+}
+
+// configDir returns the directory of the configuration file, which paths in the configuration are relative to
+func configDir() string {
+	dir, _ := filepath.Abs(filepath.Dir(configFile))
+	return dir
+}
+
+func isJSONConfig(file string) bool {
+	extension := strings.ToLower(filepath.Ext(file))
+	return extension == ".json" || extension == ".jsonc" || extension == ".json5"
+}
+
+// configToJSON converts the content of a configuration file to JSON based on its extension. JSON files may
+// contain comments, trailing commas and other JSON5 syntax.
+func configToJSON(file string, content []byte) ([]byte, error) {
+	var data interface{}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(content, &data); err != nil {
+			return nil, err
+		}
+	case ".toml":
+		if _, err := toml.Decode(string(content), &data); err != nil {
+			return nil, err
+		}
+	default:
+		return normalizeJSON(content), nil
+	}
+
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return json.Marshal(data)
+}
+
+// normalizeJSON converts JSON with comments and JSON5 syntax (trailing commas, unquoted keys, single quoted
+// strings, hexadecimal numbers and leading or trailing decimal points) to JSON
+func normalizeJSON(content []byte) []byte {
+	normalized, _ := normalizeJSONOffsets(content)
+	return normalized
+}
+
+// normalizeJSONOffsets converts JSON5 to JSON like normalizeJSON and returns the offset in content of every byte
+// of the JSON, so positions in the JSON can be reported in the original file. The bytes of a string or number map
+// to its first character.
+func normalizeJSONOffsets(content []byte) ([]byte, []int) {
+	var out bytes.Buffer
+	var offsets []int
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		start := i
+		switch {
+		case c == '"' || c == '\'':
+			i = writeJSONString(&out, content, i)
+		case c == '/' && i+1 < len(content) && content[i+1] == '/':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			out.WriteByte('\n')
+		case c == '/' && i+1 < len(content) && content[i+1] == '*':
+			end := bytes.Index(content[i+2:], []byte("*/"))
+			if end < 0 {
+				end = len(content) - i - 2
+			}
+			comment := content[i : i+2+end]
+			out.Write(bytes.Repeat([]byte("\n"), bytes.Count(comment, []byte("\n"))))
+			i += end + 3
+		case c == ',' && strings.IndexByte("]}", nextJSONToken(content, i+1)) > -1:
+			out.WriteByte(' ')
+		case isJSONIdentifierStart(c):
+			start := i
+			for i+1 < len(content) && isJSONIdentifierPart(content[i+1]) {
+				i++
+			}
+			word := string(content[start : i+1])
+			if word == "true" || word == "false" || word == "null" || nextJSONToken(content, i+1) != ':' {
+				out.WriteString(word)
+			} else {
+				fmt.Fprintf(&out, "%q", word)
+			}
+		case c == '+' || c == '.' || (c >= '0' && c <= '9'):
+			i = writeJSONNumber(&out, content, i)
+		default:
+			out.WriteByte(c)
+		}
+		for len(offsets) < out.Len() {
+			offsets = append(offsets, start)
+		}
+	}
+	return out.Bytes(), append(offsets, len(content))
+}
+
+// writeJSONString writes a double or single quoted string as a JSON string and returns the index of its last quote
+func writeJSONString(out *bytes.Buffer, content []byte, start int) int {
+	quote := content[start]
+	out.WriteByte('"')
+	i := start + 1
+	for ; i < len(content) && content[i] != quote; i++ {
+		switch {
+		case content[i] == '\\' && i+1 < len(content):
+			i++
+			switch content[i] {
+			case '\'':
+				out.WriteByte('\'')
+			case '\n':
+				// Escaped line breaks continue the string on the next line
+			default:
+				out.WriteByte('\\')
+				out.WriteByte(content[i])
+			}
+		case content[i] == '"':
+			out.WriteString("\\\"")
+		default:
+			out.WriteByte(content[i])
+		}
+	}
+	out.WriteByte('"')
+	return i
+}
+
+// writeJSONNumber writes a JSON5 number as a JSON number and returns the index of its last character
+func writeJSONNumber(out *bytes.Buffer, content []byte, start int) int {
+	end := start
+	for end < len(content) && strings.IndexByte("+-.0123456789abcdefABCDEFxX", content[end]) > -1 {
+		end++
+	}
+	number := strings.TrimPrefix(string(content[start:end]), "+")
+
+	var value int64
+	if _, err := fmt.Sscanf(strings.ToLower(number), "0x%x", &value); err == nil && strings.HasPrefix(strings.ToLower(number), "0x") {
+		fmt.Fprintf(out, "%d", value)
+		return end - 1
+	}
+	if strings.HasPrefix(number, ".") {
+		number = fmt.Sprintf("0%s", number)
+	}
+	if strings.HasSuffix(number, ".") {
+		number = fmt.Sprintf("%s0", number)
+	}
+	out.WriteString(number)
+	return end - 1
+}
+
+// nextJSONToken returns the next character after whitespace and comments, or 0 at the end of the content
+func nextJSONToken(content []byte, start int) byte {
+	for i := start; i < len(content); i++ {
+		switch {
+		case content[i] == ' ' || content[i] == '\t' || content[i] == '\r' || content[i] == '\n':
+			continue
+		case content[i] == '/' && i+1 < len(content) && content[i+1] == '/':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+		case content[i] == '/' && i+1 < len(content) && content[i+1] == '*':
+			end := bytes.Index(content[i+2:], []byte("*/"))
+			if end < 0 {
+				return 0
+			}
+			i += end + 3
+		default:
+			return content[i]
+		}
+	}
+	return 0
+}
+
+func isJSONIdentifierStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isJSONIdentifierPart(c byte) bool {
+	return isJSONIdentifierStart(c) || (c >= '0' && c <= '9') || c == '-'
+}
+
+// configErrorPosition returns the line and column in a JSON configuration file of an error decoding its converted
+// content, if the error has an offset
+func configErrorPosition(content []byte, err error) (int, int, bool) {
+	var offset int64
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		offset = e.Offset
+	case *json.UnmarshalTypeError:
+		offset = e.Offset
+	default:
+		return 0, 0, false
+	}
+	line, column := offsetPosition(content, sourceOffset(content, int(offset)))
+	return line, column, true
+}
+
+// sourceOffset returns the offset in a JSON configuration file of an offset in its converted content
+func sourceOffset(content []byte, offset int) int {
+	_, offsets := normalizeJSONOffsets(content)
+	if offset < 0 || offset >= len(offsets) {
+		return len(content)
+	}
+	return offsets[offset]
+}
+
+// offsetPosition returns the line and column of an offset in content
+func offsetPosition(content []byte, offset int) (int, int) {
+	if offset > len(content) {
+		offset = len(content)
+	}
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := offset - bytes.LastIndexByte(before, '\n')
+	return line, column
+}
+
+// configPath returns the path of a key in an object at path, i.e. 'tasks.Scripts'
+func configPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return fmt.Sprintf("%s.%s", path, key)
+}
+
+// configPosition is a line and column in a configuration file
+type configPosition struct {
+	line   int
+	column int
+}
+
+// configPositions returns the positions of the keys and list items of a configuration file by their path. TOML
+// files have no positions as the TOML decoder does not expose the positions of keys.
+func configPositions(file string, content []byte) map[string]configPosition {
+	positions := make(map[string]configPosition)
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		var root yaml.Node
+		if yaml.Unmarshal(content, &root) == nil {
+			yamlPositions(&root, "", positions)
+		}
+	case ".toml":
+	default:
+		jsonPositions(content, positions)
+	}
+	return positions
+}
+
+func yamlPositions(node *yaml.Node, path string, positions map[string]configPosition) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			yamlPositions(child, path, positions)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			child := configPath(path, key.Value)
+			positions[child] = configPosition{key.Line, key.Column}
+			yamlPositions(node.Content[i+1], child, positions)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			positions[child] = configPosition{item.Line, item.Column}
+			yamlPositions(item, child, positions)
+		}
+	case yaml.AliasNode:
+		if node.Alias != nil {
+			yamlPositions(node.Alias, path, positions)
+		}
+	}
+}
+
+func jsonPositions(content []byte, positions map[string]configPosition) {
+	jsonContent, offsets := normalizeJSONOffsets(content)
+	decoder := json.NewDecoder(bytes.NewReader(jsonContent))
+	var walk func(path string) error
+	walk = func(path string) error {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		delim, ok := token.(json.Delim)
+		if !ok {
+			return nil
+		}
+
+		for i := 0; decoder.More(); i++ {
+			// The offset is after the previous token, so separators and whitespace are skipped
+			offset := int(decoder.InputOffset())
+			for offset < len(jsonContent) && strings.IndexByte(" \t\r\n,:", jsonContent[offset]) > -1 {
+				offset++
+			}
+
+			child := fmt.Sprintf("%s[%d]", path, i)
+			if delim == '{' {
+				key, err := decoder.Token()
+				if err != nil {
+					return err
+				}
+				child = configPath(path, fmt.Sprint(key))
+			}
+			line, column := offsetPosition(content, offsets[offset])
+			positions[child] = configPosition{line, column}
+			if err = walk(child); err != nil {
+				return err
+			}
+		}
+		_, err = decoder.Token()
+		return err
+	}
+	walk("")
+}
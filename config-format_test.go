@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		json    string
+	}{
+		{"plain JSON", `{"a": [1, "b", true, null]}`, `{"a": [1, "b", true, null]}`},
+		{"line comments", "{\n  // comment\n  \"a\": 1 // trailing\n}", `{"a": 1}`},
+		{"block comments", "{ /* a: 2, */ \"a\": /* inline */ 1 }", `{"a": 1}`},
+		{"comment markers in strings", `{"a": "// not a comment /* either */"}`, `{"a": "// not a comment /* either */"}`},
+		{"trailing commas", "{\"a\": [1, 2,], \"b\": {\"c\": 3,},}", `{"a": [1, 2], "b": {"c": 3}}`},
+		{"trailing comma before comment", "[1, // one\n]", `[1]`},
+		{"unquoted keys", `{srcDir: "./src", $schema: "x", build_dir: "./build"}`, `{"srcDir": "./src", "$schema": "x", "build_dir": "./build"}`},
+		{"single quoted strings", `{'a': 'it\'s "quoted"'}`, `{"a": "it's \"quoted\""}`},
+		{"hexadecimal numbers", `{a: 0x1F, b: 0XFF}`, `{"a": 31, "b": 255}`},
+		{"decimal points", `{a: .5, b: 5., c: +1, d: -2.5}`, `{"a": 0.5, "b": 5.0, "c": 1, "d": -2.5}`},
+		{"line continuations", "{a: 'one \\\ntwo'}", `{"a": "one two"}`},
+	}
+	for _, test := range tests {
+		var got, want interface{}
+		if err := json.Unmarshal(normalizeJSON([]byte(test.content)), &got); err != nil {
+			t.Errorf("%s: normalizeJSON() is not valid JSON: %s", test.name, err)
+			continue
+		}
+		json.Unmarshal([]byte(test.json), &want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: normalizeJSON() = %v, want %v", test.name, got, want)
+		}
+	}
+}
+
+func TestConfigDecodeErrorPosition(t *testing.T) {
+	tests := []struct {
+		file    string
+		content string
+		err     string
+	}{
+		{"web-build.json", "{\n  \"a\": Infinity\n}", "web-build.json:2:8: invalid character 'I' looking for beginning of value"},
+		{"web-build.json5", "{\n  /* comment */ a: Infinity\n}", "web-build.json5:2:20: invalid character 'I' looking for beginning of value"},
+		{"web-build.jsonc", "{\n  // comment\n  'a': 0x10, b: NaN\n}", "web-build.jsonc:3:17: invalid character 'N' looking for beginning of value"},
+	}
+	for _, test := range tests {
+		var data interface{}
+		err := json.Unmarshal(normalizeJSON([]byte(test.content)), &data)
+		if err == nil {
+			t.Errorf("%s: expected an error for %q", test.file, test.content)
+			continue
+		}
+		if got := configDecodeError(test.file, []byte(test.content), err).Error(); got != test.err {
+			t.Errorf("%s: configDecodeError() = %q, want %q", test.file, got, test.err)
+		}
+	}
+}
+
+func TestConfigPositions(t *testing.T) {
+	tests := []struct {
+		file      string
+		content   string
+		positions map[string]configPosition
+	}{
+		{"web-build.json", "{\n  \"srcDir\": \"./src\",\n  \"tasks\": {\"A\": {\"globs\": [\".js\"]}}\n}", map[string]configPosition{
+			"srcDir": {2, 3}, "tasks": {3, 3}, "tasks.A": {3, 13}, "tasks.A.globs": {3, 19}, "tasks.A.globs[0]": {3, 29},
+		}},
+		{"web-build.json5", "{\n  /* comment */ srcDir: './src', /* comment */ buildDir: 0x1,\n  // comment\n  tasks: {},\n}", map[string]configPosition{
+			"srcDir": {2, 17}, "buildDir": {2, 48}, "tasks": {4, 3},
+		}},
+		{"web-build.json5", "{a: 'one \\\ntwo', b: 1}", map[string]configPosition{
+			"a": {1, 2}, "b": {2, 7},
+		}},
+		{"web-build.yaml", "srcDir: ./src\ntasks:\n  A:\n    globs:\n      - .js\n", map[string]configPosition{
+			"srcDir": {1, 1}, "tasks": {2, 1}, "tasks.A": {3, 3}, "tasks.A.globs": {4, 5}, "tasks.A.globs[0]": {5, 9},
+		}},
+		{"web-build.toml", "srcDir = \"./src\"\n", map[string]configPosition{}},
+	}
+	for _, test := range tests {
+		if positions := configPositions(test.file, []byte(test.content)); !reflect.DeepEqual(positions, test.positions) {
+			t.Errorf("%s: configPositions(%q) = %v, want %v", test.file, test.content, positions, test.positions)
+		}
+	}
+}
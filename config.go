@@ -3,10 +3,13 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
+	"strings"
 )
 
-var validActions = []string{"collate", "concat", "js-minify", "sass", "shell"}
+var validActions = []string{"collate", "concat", "js-minify", "sass", "shell", "png-sprite", "favicons", "bundle", "css-bundle", "css-prefix", "css-purge", "markdown", "json-merge", "i18n", "sitemap", "precache-manifest", "sri", "html-inject", "critical-css", "inline"}
+
+// buildDirActions are the actions that work with the files in the build directory when they have no input files
+var buildDirActions = []string{"sitemap", "precache-manifest", "sri", "critical-css", "inline"}
 
 // Config defines the struct for the User Configuration file
 type Config struct {
@@ -14,38 +17,60 @@ type Config struct {
 	SrcDir          string
 	BuildDir        string
 	Tasks           map[string]Task
-	Targets         map[string]struct {
-		Dependency string
-	}
-	Target string
+	Targets         map[string]Target
+	Target          string
+	Browsers        []string
+	Locales         []string
+	DefaultLocale   string
+	Version         string
+	Profile         string
+	Profiles        map[string]Profile
+}
+
+// Target defines the struct for a build target
+type Target struct {
+	Dependency string
+	Settings   map[string]interface{}
 }
 
 // Task defines the struct for a task
 type Task struct {
-	Actions []Action
-	Targets []string
-	Globs   []string
+	Actions  []Action
+	Targets  []string
+	Globs    []string
+	After    []string
+	Profiles []string
 }
 
 // Action defines the struct for a specific action to perform in a task
 type Action struct {
-	Action  string
-	Targets []string
-	Options map[string]interface{}
+	Action   string
+	Targets  []string
+	Profiles []string
+	Options  map[string]interface{}
 }
 
-const configFile = "./web-build.json"
+// configFile is the configuration file in use. It is found by resolveConfig.
+var configFile = "./web-build.json"
 
-func parseConfig() (Config, error) {
+// parseConfig parses the configuration for a target. The target selects the .env file used for variables in the
+// configuration and defaults to the target in the configuration.
+func parseConfig(target string) (Config, error) {
 	var unmarshalledData Config
-	jsonContent, err := ioutil.ReadFile(configFile)
+	data, err := resolveConfig()
+	if err == nil {
+		data, err = interpolateConfig(data, target)
+	}
 	if err != nil {
 		return unmarshalledData, err
 	}
 
-	err = json.Unmarshal(jsonContent, &unmarshalledData)
+	jsonContent, err := json.Marshal(data)
+	if err == nil {
+		err = json.Unmarshal(jsonContent, &unmarshalledData)
+	}
 	if err != nil {
-		return unmarshalledData, err
+		return unmarshalledData, fmt.Errorf("%s: %s", configFile, err)
 	}
 
 	if !checkValidTarget(unmarshalledData.Target, unmarshalledData) {
@@ -56,6 +81,18 @@ func parseConfig() (Config, error) {
 		return unmarshalledData, err
 	}
 
+	if _, err := checkValidTaskOrder(unmarshalledData.Tasks); err != nil {
+		return unmarshalledData, err
+	}
+
+	if argProfile != "" {
+		unmarshalledData.Profile = argProfile
+	}
+	if err := checkValidProfiles(unmarshalledData); err != nil {
+		return unmarshalledData, err
+	}
+	applyProfile(&unmarshalledData)
+
 	return unmarshalledData, err
 }
 
@@ -69,3 +106,72 @@ func checkValidActions(tasks map[string]Task) (bool, error) {
 	}
 	return true, nil
 }
+
+func checkValidTaskOrder(tasks map[string]Task) (bool, error) {
+	for taskName, task := range tasks {
+		for _, after := range task.After {
+			if _, ok := tasks[after]; !ok && after != "*" {
+				return false, fmt.Errorf("task '%s' runs after undefined task '%s'", taskName, after)
+			}
+		}
+		for _, action := range task.Actions {
+			if action.Action != "html-inject" {
+				continue
+			}
+			for _, injected := range optionStrings(action.Options, "tasks") {
+				if _, ok := tasks[injected]; !ok {
+					return false, fmt.Errorf("'html-inject' in task '%s' injects the outputs of undefined task '%s'", taskName, injected)
+				}
+			}
+		}
+	}
+
+	// Walk the tasks each task runs after to find cycles that would block forever
+	var visit func(name string, chain []string) error
+	visit = func(name string, chain []string) error {
+		if stringInSlice(name, chain) {
+			return fmt.Errorf("circular task order '%s'", strings.Join(append(chain, name), "' -> '"))
+		}
+		for _, after := range taskDependencies(name, tasks) {
+			if err := visit(after, append(chain, name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for taskName := range tasks {
+		if err := visit(taskName, nil); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// taskDependencies returns the names of the tasks a task runs after. The name "*" stands for every task that
+// does not itself run after "*". The tasks whose outputs an 'html-inject' action injects run before it as well.
+func taskDependencies(name string, tasks map[string]Task) []string {
+	var dependencies []string
+	for _, after := range tasks[name].After {
+		if after != "*" {
+			dependencies = append(dependencies, after)
+			continue
+		}
+		for taskName, task := range tasks {
+			if taskName != name && !stringInSlice("*", task.After) && !stringInSlice(taskName, dependencies) {
+				dependencies = append(dependencies, taskName)
+			}
+		}
+	}
+	for _, action := range tasks[name].Actions {
+		if action.Action != "html-inject" {
+			continue
+		}
+		for _, injected := range optionStrings(action.Options, "tasks") {
+			if injected != name && !stringInSlice(injected, dependencies) {
+				dependencies = append(dependencies, injected)
+			}
+		}
+	}
+	return dependencies
+}
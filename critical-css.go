@@ -0,0 +1,270 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	nethtml "golang.org/x/net/html"
+)
+
+type criticalCSSAction struct{}
+
+var criticalPseudoRegex = regexp.MustCompile(`(::?)(-?[a-zA-Z][a-zA-Z0-9-]*)(\([^)]*\))?`)
+var criticalFontFamilyRegex = regexp.MustCompile(`(?i)font-family\s*:\s*([^;}]+)`)
+
+// criticalDynamicPseudos depend on user interaction or are not elements of the document, so they are
+// removed from selectors before matching
+var criticalDynamicPseudos = []string{"hover", "focus", "focus-within", "focus-visible", "active", "visited", "link", "any-link", "target", "before", "after", "first-line", "first-letter"}
+
+func (action criticalCSSAction) Action(files []string, options map[string]interface{}) (outputFiles []string) {
+	// Without input files the HTML files of the build directory are used
+	if len(files) == 0 {
+		buildFiles, _, err := filesInPath(config.BuildDir)
+		if err != nil {
+			errorMsg(fmt.Sprintf("Folder '%s' not found.", config.BuildDir), err)
+			return files
+		}
+		globs := optionStrings(options, "html")
+		if len(globs) == 0 {
+			globs = []string{".html"}
+		}
+		files = globFiles(globs, config.BuildDir, buildFiles)
+	}
+
+	maxSize := optionInt(options, "maxSize", 14336)
+	var unresolved []string
+	var truncated []string
+
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not read file '%s'", file), err)
+			continue
+		}
+
+		newFile := file
+		if !strings.HasPrefix(file, config.BuildDir) {
+			newFile = fmt.Sprintf("%s%s", config.BuildDir, relativePath(file))
+		}
+
+		document, err := nethtml.Parse(bytes.NewReader(content))
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not parse HTML file '%s'", file), err)
+			continue
+		}
+		matches := selectorMatcher(document)
+
+		var critical []*cssNode
+		inserted := false
+		updated := linkTagRegex.ReplaceAllStringFunc(string(content), func(tag string) string {
+			attributes := tagAttributes(tag)
+			if !isStylesheetLink(attributes) || isRemoteURL(attributes["href"]) {
+				return tag
+			}
+			// Stylesheets already loading asynchronously have been processed before
+			if _, ok := attributes["onload"]; ok {
+				return tag
+			}
+
+			stylesheet, ok := resolveBuildReference(newFile, attributes["href"])
+			if !ok {
+				return tag
+			}
+			css, err := ioutil.ReadFile(stylesheet)
+			if err != nil {
+				unresolved = append(unresolved, fmt.Sprintf("%s: %s", relativePath(newFile), attributes["href"]))
+				return tag
+			}
+
+			// URLs in the stylesheet are made relative to the page the rules are inlined in
+			css = rewriteCSSURLs(css, stylesheet, newFile, 0)
+			critical = append(critical, criticalRules(parseCSS(string(css)), matches)...)
+			if !inserted {
+				inserted = true
+				return fmt.Sprintf("%s%s<noscript>%s</noscript>", criticalPlaceholder, asyncStylesheetTag(tag, attributes["media"]), tag)
+			}
+			return fmt.Sprintf("%s<noscript>%s</noscript>", asyncStylesheetTag(tag, attributes["media"]), tag)
+		})
+		if !inserted {
+			continue
+		}
+
+		styles, ok := limitCriticalCSS(critical, maxSize)
+		if !ok {
+			truncated = append(truncated, relativePath(newFile))
+		}
+
+		// The inlined styles use the indentation of the first stylesheet link
+		index := strings.Index(updated, criticalPlaceholder)
+		lineStart := strings.LastIndex(updated[:index], "\n") + 1
+		indent := updated[lineStart:index]
+		if strings.TrimSpace(indent) != "" {
+			indent = ""
+		}
+		style := fmt.Sprintf("<style>\n%s%s</style>\n%s", styles, indent, indent)
+		updated = strings.Replace(updated, criticalPlaceholder, style, 1)
+
+		err = os.MkdirAll(filepath.Dir(newFile), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(newFile, []byte(updated), 0644)
+		}
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not write to '%s' in 'critical-css' action.", newFile), err)
+			continue
+		}
+		outputFiles = append(outputFiles, newFile)
+	}
+
+	if len(unresolved) > 0 {
+		sort.Strings(unresolved)
+		errorMsg(fmt.Sprintf("Could not resolve the following stylesheets in 'critical-css' action:\n  %s", strings.Join(unresolved, "\n  ")), nil)
+	}
+	if len(truncated) > 0 {
+		errorMsg(fmt.Sprintf("Critical CSS exceeded 'maxSize' of %d bytes and was truncated for:\n  %s", maxSize, strings.Join(truncated, "\n  ")), nil)
+	}
+
+	return outputFiles
+}
+
+const criticalPlaceholder = "<!--critical-css-->"
+
+// selectorMatcher returns a function reporting whether a selector matches any element of a document.
+// Selectors that cannot be compiled are assumed to match.
+func selectorMatcher(document *nethtml.Node) func(string) bool {
+	cache := make(map[string]bool)
+	return func(selector string) bool {
+		selector = staticSelector(selector)
+		if matched, ok := cache[selector]; ok {
+			return matched
+		}
+		matched := true
+		if compiled, err := cascadia.Compile(selector); err == nil {
+			matched = compiled.MatchFirst(document) != nil
+		}
+		cache[selector] = matched
+		return matched
+	}
+}
+
+// staticSelector removes dynamic pseudo-classes and pseudo-elements from a selector so it can be matched
+// against the document
+func staticSelector(selector string) string {
+	selector = criticalPseudoRegex.ReplaceAllStringFunc(selector, func(pseudo string) string {
+		submatches := criticalPseudoRegex.FindStringSubmatch(pseudo)
+		name := strings.ToLower(submatches[2])
+		if submatches[1] == "::" || strings.HasPrefix(name, "-") || stringInSlice(name, criticalDynamicPseudos) {
+			return ""
+		}
+		return pseudo
+	})
+
+	selector = strings.TrimSpace(selector)
+	if selector == "" || strings.ContainsAny(selector[len(selector)-1:], ">+~") {
+		selector = fmt.Sprintf("%s *", selector)
+	}
+	return selector
+}
+
+// criticalRules returns the rules with at least one selector matching the document. Font faces and keyframes
+// are kept when they are referenced by the matching rules.
+func criticalRules(nodes []*cssNode, matches func(string) bool) []*cssNode {
+	var fonts, keyframes []*cssNode
+	rules := matchingRules(nodes, matches, &fonts, &keyframes)
+
+	css := serializeCSS(rules)
+	var families []string
+	for _, match := range criticalFontFamilyRegex.FindAllStringSubmatch(css, -1) {
+		for _, family := range strings.Split(match[1], ",") {
+			families = append(families, strings.ToLower(strings.Trim(strings.TrimSpace(family), `"'`)))
+		}
+	}
+
+	var result []*cssNode
+	for _, font := range fonts {
+		for _, declaration := range font.children {
+			family := strings.ToLower(strings.Trim(strings.TrimSpace(declaration.value), `"'`))
+			if declaration.name == "font-family" && stringInSlice(family, families) {
+				result = append(result, font)
+				break
+			}
+		}
+	}
+	for _, keyframe := range keyframes {
+		if regexp.MustCompile(fmt.Sprintf(`animation(?:-name)?\s*:[^;}]*\b%s\b`, regexp.QuoteMeta(keyframe.prelude))).MatchString(css) {
+			result = append(result, keyframe)
+		}
+	}
+	return append(result, rules...)
+}
+
+func matchingRules(nodes []*cssNode, matches func(string) bool, fonts, keyframes *[]*cssNode) []*cssNode {
+	var result []*cssNode
+	for _, node := range nodes {
+		switch {
+		case node.nodeType == cssRule:
+			var kept []string
+			for _, selector := range splitSelectors(node.prelude) {
+				if matches(selector) {
+					kept = append(kept, selector)
+				}
+			}
+			if len(kept) == 0 {
+				continue
+			}
+			rule := *node
+			rule.prelude = strings.Join(kept, ", ")
+			result = append(result, &rule)
+		case node.nodeType == cssAtRule && node.block && isConditionalAtRule(node.name):
+			children := matchingRules(node.children, matches, fonts, keyframes)
+			if len(children) == 0 {
+				continue
+			}
+			rule := *node
+			rule.children = children
+			result = append(result, &rule)
+		case node.nodeType == cssAtRule && node.name == "font-face":
+			*fonts = append(*fonts, node)
+		case node.nodeType == cssAtRule && strings.HasSuffix(node.name, "keyframes"):
+			*keyframes = append(*keyframes, node)
+		}
+	}
+	return result
+}
+
+// limitCriticalCSS serializes the rules in order until the maximum size is reached. It reports false if rules
+// had to be left out.
+func limitCriticalCSS(nodes []*cssNode, maxSize int) (string, bool) {
+	var parts []string
+	size := 0
+	for _, node := range nodes {
+		part := serializeCSS([]*cssNode{node})
+		if maxSize > 0 && size+len(part)+1 > maxSize {
+			return strings.Join(parts, "\n"), false
+		}
+		parts = append(parts, part)
+		size += len(part) + 1
+	}
+	return strings.Join(parts, "\n"), true
+}
+
+// asyncStylesheetTag changes a stylesheet link to load without blocking rendering by loading it for print and
+// switching it to its original media once loaded
+func asyncStylesheetTag(tag, media string) string {
+	if media == "" {
+		media = "all"
+	}
+	tag = setTagAttribute(tag, "media", "print")
+	end := len(tag) - 1
+	if strings.HasSuffix(tag, " />") {
+		end = len(tag) - 3
+	}
+	return fmt.Sprintf("%s onload=\"this.onload=null;this.media='%s'\"%s", tag[:end], html.EscapeString(media), tag[end:])
+}
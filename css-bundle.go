@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type cssBundleAction struct{}
+
+var cssImportRegex = regexp.MustCompile(`@import\s+(?:url\(\s*)?["']?([^"')\s;]+)["']?\s*\)?\s*([^;]*);`)
+var cssURLRegex = regexp.MustCompile(`url\(\s*(["']?)([^"')]+)(["']?)\s*\)`)
+
+func (action cssBundleAction) Action(files []string, options map[string]interface{}) (outputFiles []string) {
+	if len(files) == 0 {
+		return files
+	}
+
+	inlineLimit := int64(optionInt(options, "inlineLimit", 0))
+
+	outputFile, single := options["output"].(string)
+	if single {
+		outputFile = fmt.Sprintf("%s%s", config.BuildDir, outputFile)
+	}
+
+	var concat bytes.Buffer
+	for i, file := range files {
+		newFile := outputFile
+		if !single {
+			newFile = fmt.Sprintf("%s%s", config.BuildDir, relativePath(file))
+		}
+
+		content, err := bundleCSSFile(file, newFile, inlineLimit, []string{})
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not bundle '%s' in 'css-bundle' action.", file), err)
+			continue
+		}
+
+		if single {
+			if i > 0 {
+				concat.WriteString("\n")
+			}
+			concat.Write(content)
+			continue
+		}
+
+		if err = writeCSSBundle(newFile, content); err != nil {
+			errorMsg(fmt.Sprintf("Could not write to '%s' in 'css-bundle' action.", newFile), err)
+			continue
+		}
+		outputFiles = append(outputFiles, newFile)
+	}
+
+	if single {
+		if err := writeCSSBundle(outputFile, concat.Bytes()); err != nil {
+			errorMsg(fmt.Sprintf("Could not write to '%s' defined in 'css-bundle' action. Skipping task...", outputFile), err)
+			return files
+		}
+		outputFiles = append(outputFiles, outputFile)
+	}
+
+	return outputFiles
+}
+
+func writeCSSBundle(file string, content []byte) error {
+	err := os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, content, 0644)
+}
+
+// bundleCSSFile inlines the local imports of a stylesheet and rewrites its relative URLs for the output file.
+// The chain of files being imported is used to detect circular imports.
+func bundleCSSFile(file, outputFile string, inlineLimit int64, chain []string) ([]byte, error) {
+	if stringInSlice(file, chain) {
+		return nil, fmt.Errorf("circular import of '%s'", file)
+	}
+	chain = append(chain, file)
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// Imports are swapped for placeholders so their URLs are not rewritten relative to the importing file
+	var imports [][]byte
+	var importErr error
+	content = cssImportRegex.ReplaceAllFunc(content, func(match []byte) []byte {
+		imports = append(imports, bundleCSSImport(match, file, outputFile, inlineLimit, chain, &importErr))
+		return []byte(fmt.Sprintf("/*__css-bundle-import-%d__*/", len(imports)-1))
+	})
+
+	content = rewriteCSSURLs(content, file, outputFile, inlineLimit)
+	for i, imported := range imports {
+		content = bytes.Replace(content, []byte(fmt.Sprintf("/*__css-bundle-import-%d__*/", i)), imported, 1)
+	}
+
+	return content, importErr
+}
+
+func bundleCSSImport(match []byte, file, outputFile string, inlineLimit int64, chain []string, importErr *error) []byte {
+	submatches := cssImportRegex.FindSubmatch(match)
+	reference := string(submatches[1])
+	media := strings.TrimSpace(string(submatches[2]))
+	if isRemoteURL(reference) {
+		return match
+	}
+
+	importFile, ok := resolveRelativeFile(file, reference)
+	if !ok {
+		errorMsg(fmt.Sprintf("Could not resolve import '%s' in '%s'.", reference, file), nil)
+		return match
+	}
+
+	imported, err := bundleCSSFile(importFile, outputFile, inlineLimit, chain)
+	if err != nil {
+		*importErr = err
+		return match
+	}
+	if media != "" {
+		return []byte(fmt.Sprintf("@media %s {\n%s\n}", media, imported))
+	}
+	return imported
+}
+
+// rewriteCSSURLs makes the relative URLs of a stylesheet relative to the file it will be written to
+func rewriteCSSURLs(content []byte, file, outputFile string, inlineLimit int64) []byte {
+	return cssURLRegex.ReplaceAllFunc(content, func(match []byte) []byte {
+		submatches := cssURLRegex.FindSubmatch(match)
+		reference := strings.TrimSpace(string(submatches[2]))
+		if isRemoteURL(reference) || strings.HasPrefix(reference, "/") || strings.HasPrefix(reference, "#") {
+			return match
+		}
+
+		reference, suffix := splitURLSuffix(reference)
+		if inlineLimit > 0 {
+			if asset, ok := resolveRelativeFile(file, reference); ok {
+				if info, err := os.Stat(asset); err == nil && info.Size() <= inlineLimit {
+					if uri, err := dataURI(asset); err == nil {
+						return []byte(fmt.Sprintf("url(\"%s\")", uri))
+					}
+				}
+			}
+		}
+
+		relative, err := filepath.Rel(filepath.Dir(outputFile), buildLocation(file, reference))
+		if err != nil {
+			return match
+		}
+		quote := string(submatches[1])
+		return []byte(fmt.Sprintf("url(%s%s%s%s)", quote, filepath.ToSlash(relative), suffix, quote))
+	})
+}
+
+// buildLocation returns where a file referenced relative to another file will be located in the build directory
+func buildLocation(file, reference string) string {
+	if strings.HasPrefix(file, config.BuildDir) {
+		return path.Join(path.Dir(file), reference)
+	}
+	return fmt.Sprintf("%s%s", config.BuildDir, path.Join(path.Dir(relativePath(file)), reference))
+}
+
+// resolveRelativeFile resolves a reference relative to a file. References from source files are resolved
+// through the build targets while references from build files are resolved in the build directory.
+func resolveRelativeFile(file, reference string) (string, bool) {
+	reference, _ = splitURLSuffix(reference)
+	if strings.HasPrefix(file, config.SrcDir) {
+		resolved, _, ok := resolveTargetPath(path.Join(path.Dir(relativePath(file)), reference))
+		return resolved, ok
+	}
+
+	resolved := path.Join(path.Dir(file), reference)
+	info, err := os.Stat(resolved)
+	return resolved, err == nil && !info.IsDir()
+}
+
+func splitURLSuffix(reference string) (string, string) {
+	if index := strings.IndexAny(reference, "?#"); index > -1 {
+		return reference[:index], reference[index:]
+	}
+	return reference, ""
+}
+
+func isRemoteURL(reference string) bool {
+	return strings.HasPrefix(reference, "//") || strings.HasPrefix(reference, "data:") || strings.Contains(reference, "://")
+}
+
+func dataURI(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	mimeType := mime.TypeByExtension(filepath.Ext(file))
+	if mimeType == "" {
+		mimeType = "application/octet-stream"
+	}
+	return fmt.Sprintf("data:%s;base64,%s", strings.Split(mimeType, ";")[0], base64.StdEncoding.EncodeToString(data)), nil
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type cssPrefixAction struct{}
+
+// never is used as the support version for browsers that still require a prefix
+const never = 9999
+
+// cssPrefix defines a vendor prefix along with the browser versions that support the feature without it
+type cssPrefix struct {
+	prefix  string
+	support map[string]float64
+}
+
+var webkitTransforms = cssPrefix{"-webkit-", map[string]float64{"chrome": 36, "safari": 9, "ios_saf": 9, "opera": 23, "samsung": 4}}
+var webkitFlexbox = cssPrefix{"-webkit-", map[string]float64{"chrome": 29, "safari": 9, "ios_saf": 9, "opera": 17}}
+
+var cssPropertyPrefixes = map[string][]cssPrefix{
+	"user-select": {
+		{"-webkit-", map[string]float64{"chrome": 54, "safari": never, "ios_saf": never, "opera": 41, "samsung": 6}},
+		{"-moz-", map[string]float64{"firefox": 69}},
+		{"-ms-", map[string]float64{"ie": never, "edge": 79}},
+	},
+	"appearance": {
+		{"-webkit-", map[string]float64{"chrome": 84, "edge": 84, "safari": 15.4, "ios_saf": 15.4, "opera": 70, "samsung": 14}},
+		{"-moz-", map[string]float64{"firefox": 80}},
+	},
+	"backdrop-filter": {{"-webkit-", map[string]float64{"safari": 18, "ios_saf": 18}}},
+	"text-size-adjust": {
+		{"-webkit-", map[string]float64{"ios_saf": never}},
+		{"-moz-", map[string]float64{"firefox": never}},
+	},
+	"hyphens": {
+		{"-webkit-", map[string]float64{"safari": 17, "ios_saf": 17}},
+		{"-ms-", map[string]float64{"ie": never, "edge": 79}},
+	},
+	"mask":                 {{"-webkit-", map[string]float64{"chrome": 120, "edge": 120, "safari": 15.4, "ios_saf": 15.4, "opera": 106, "samsung": 25}}},
+	"mask-image":           {{"-webkit-", map[string]float64{"chrome": 120, "edge": 120, "safari": 15.4, "ios_saf": 15.4, "opera": 106, "samsung": 25}}},
+	"box-decoration-break": {{"-webkit-", map[string]float64{"chrome": 130, "edge": 130, "safari": never, "ios_saf": never, "opera": 115}}},
+	"clip-path":            {{"-webkit-", map[string]float64{"chrome": 55, "safari": 13.1, "ios_saf": 13, "opera": 42, "samsung": 6}}},
+	"tab-size":             {{"-moz-", map[string]float64{"firefox": 91}}},
+	"transform":            {webkitTransforms},
+	"transform-origin":     {webkitTransforms},
+	"transition":           {webkitTransforms},
+	"animation":            {webkitTransforms},
+	"animation-name":       {webkitTransforms},
+	"animation-duration":   {webkitTransforms},
+	"animation-delay":      {webkitTransforms},
+	"flex":                 {webkitFlexbox},
+	"flex-direction":       {webkitFlexbox},
+	"flex-wrap":            {webkitFlexbox},
+	"flex-grow":            {webkitFlexbox},
+	"flex-shrink":          {webkitFlexbox},
+	"flex-basis":           {webkitFlexbox},
+	"justify-content":      {webkitFlexbox},
+	"align-items":          {webkitFlexbox},
+	"align-self":           {webkitFlexbox},
+	"order":                {webkitFlexbox},
+}
+
+var cssValuePrefixes = map[string]map[string][]cssPrefix{
+	"display": {
+		"flex":        {webkitFlexbox},
+		"inline-flex": {webkitFlexbox},
+	},
+	"position": {
+		"sticky": {{"-webkit-", map[string]float64{"safari": 13, "ios_saf": 13}}},
+	},
+}
+
+var cssSelectorPrefixes = map[string][]struct {
+	replacement string
+	support     map[string]float64
+}{
+	"::placeholder": {
+		{"::-webkit-input-placeholder", map[string]float64{"chrome": 57, "safari": 10.1, "ios_saf": 10.3, "opera": 44, "samsung": 7}},
+		{"::-moz-placeholder", map[string]float64{"firefox": 51}},
+		{":-ms-input-placeholder", map[string]float64{"ie": never, "edge": 79}},
+	},
+	"::selection": {
+		{"::-moz-selection", map[string]float64{"firefox": 62}},
+	},
+}
+
+var keyframesSupport = map[string]float64{"chrome": 43, "safari": 9, "ios_saf": 9, "opera": 30, "samsung": 4}
+var nestingSupport = map[string]float64{"chrome": 120, "edge": 120, "firefox": 117, "safari": 17.2, "ios_saf": 17.2, "opera": 106, "samsung": 25, "ie": never}
+
+var customMediaRegex = regexp.MustCompile(`\((--[a-zA-Z0-9_-]+)\)`)
+
+func (action cssPrefixAction) Action(files []string, options map[string]interface{}) (outputFiles []string) {
+	if len(files) == 0 {
+		return files
+	}
+
+	queries := optionStrings(options, "browsers")
+	if len(queries) == 0 {
+		queries = config.Browsers
+	}
+	if len(queries) == 0 {
+		queries = []string{"defaults"}
+	}
+
+	targets, err := parseBrowserQuery(queries)
+	if err != nil {
+		errorMsg("Invalid browsers query in 'css-prefix' action. Skipping task...", err)
+		return files
+	}
+
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not read file '%s'", file), err)
+			continue
+		}
+
+		nodes := parseCSS(string(content))
+		nodes = lowerCustomMedia(nodes)
+		if targets.olderThan(nestingSupport) {
+			nodes = lowerNesting(nodes, nil)
+		}
+		nodes = prefixCSS(nodes, targets)
+
+		// Source files are written to the build directory. Build files are modified in place.
+		newFile := file
+		if !strings.HasPrefix(file, config.BuildDir) {
+			newFile = fmt.Sprintf("%s%s", config.BuildDir, relativePath(file))
+		}
+
+		err = os.MkdirAll(filepath.Dir(newFile), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(newFile, []byte(serializeCSSLike(nodes, string(content))), 0644)
+		}
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not write to '%s' in 'css-prefix' action.", newFile), err)
+			continue
+		}
+		outputFiles = append(outputFiles, newFile)
+	}
+	return outputFiles
+}
+
+// lowerCustomMedia replaces custom media queries with their definitions and removes the definitions
+func lowerCustomMedia(nodes []*cssNode) []*cssNode {
+	definitions := make(map[string]string)
+	var result []*cssNode
+	for _, node := range nodes {
+		if node.nodeType == cssAtRule && node.name == "custom-media" && !node.block {
+			fields := strings.Fields(node.prelude)
+			if len(fields) > 1 {
+				definitions[fields[0]] = strings.TrimSpace(strings.TrimPrefix(node.prelude, fields[0]))
+				continue
+			}
+		}
+		result = append(result, node)
+	}
+
+	if len(definitions) == 0 {
+		return result
+	}
+
+	var replace func(nodes []*cssNode)
+	replace = func(nodes []*cssNode) {
+		for _, node := range nodes {
+			if node.nodeType == cssAtRule && node.name == "media" {
+				node.prelude = customMediaRegex.ReplaceAllStringFunc(node.prelude, func(match string) string {
+					if definition, ok := definitions[match[1:len(match)-1]]; ok {
+						return definition
+					}
+					return match
+				})
+			}
+			replace(node.children)
+		}
+	}
+	replace(result)
+	return result
+}
+
+// lowerNesting flattens nested rules. Nested at-rules are hoisted above the rule with the rule placed inside.
+func lowerNesting(nodes []*cssNode, parents []string) []*cssNode {
+	var result []*cssNode
+	for _, node := range nodes {
+		switch {
+		case node.nodeType == cssRule:
+			selectors := resolveNestedSelectors(splitSelectors(node.prelude), parents)
+			flattened := &cssNode{nodeType: cssRule, prelude: strings.Join(selectors, ", "), block: true}
+			var nested []*cssNode
+			for _, child := range node.children {
+				if child.nodeType == cssDeclaration || child.nodeType == cssComment {
+					flattened.children = append(flattened.children, child)
+				} else {
+					nested = append(nested, child)
+				}
+			}
+			if len(flattened.children) > 0 || len(nested) == 0 {
+				result = append(result, flattened)
+			}
+			result = append(result, lowerNesting(nested, selectors)...)
+		case node.nodeType == cssAtRule && node.block && len(parents) > 0:
+			// Declarations directly inside of a nested at-rule belong to the parent selector
+			hoisted := &cssNode{nodeType: cssAtRule, name: node.name, prelude: node.prelude, block: true}
+			wrapper := &cssNode{nodeType: cssRule, prelude: "&", block: true, children: node.children}
+			hoisted.children = lowerNesting([]*cssNode{wrapper}, parents)
+			result = append(result, hoisted)
+		case node.nodeType == cssAtRule && node.block && isConditionalAtRule(node.name):
+			node.children = lowerNesting(node.children, nil)
+			result = append(result, node)
+		default:
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+func resolveNestedSelectors(selectors, parents []string) []string {
+	if len(parents) == 0 {
+		return selectors
+	}
+
+	var resolved []string
+	for _, parent := range parents {
+		for _, selector := range selectors {
+			if strings.Contains(selector, "&") {
+				resolved = append(resolved, strings.Replace(selector, "&", parent, -1))
+			} else {
+				resolved = append(resolved, fmt.Sprintf("%s %s", parent, selector))
+			}
+		}
+	}
+	return resolved
+}
+
+func isConditionalAtRule(name string) bool {
+	return name == "media" || name == "supports" || name == "layer" || name == "container"
+}
+
+// prefixCSS adds vendor prefixed declarations, values, selectors and keyframes required by the targeted browsers
+func prefixCSS(nodes []*cssNode, targets browserTargets) []*cssNode {
+	var result []*cssNode
+	for _, node := range nodes {
+		switch node.nodeType {
+		case cssDeclaration:
+			result = append(result, prefixDeclaration(node, nodes, targets)...)
+			continue
+		case cssRule:
+			node.children = prefixCSS(node.children, targets)
+			result = append(result, prefixSelector(node, targets)...)
+			continue
+		case cssAtRule:
+			node.children = prefixCSS(node.children, targets)
+			if node.name == "keyframes" && targets.olderThan(keyframesSupport) {
+				result = append(result, &cssNode{nodeType: cssAtRule, name: "-webkit-keyframes", prelude: node.prelude, block: true, children: node.children})
+			}
+		}
+		result = append(result, node)
+	}
+	return result
+}
+
+func prefixDeclaration(node *cssNode, siblings []*cssNode, targets browserTargets) []*cssNode {
+	var result []*cssNode
+	for _, prefix := range cssPropertyPrefixes[node.name] {
+		name := fmt.Sprintf("%s%s", prefix.prefix, node.name)
+		if targets.olderThan(prefix.support) && !hasDeclaration(siblings, name, "") {
+			result = append(result, &cssNode{nodeType: cssDeclaration, name: name, value: node.value})
+		}
+	}
+	for _, prefix := range cssValuePrefixes[node.name][node.value] {
+		value := fmt.Sprintf("%s%s", prefix.prefix, node.value)
+		if targets.olderThan(prefix.support) && !hasDeclaration(siblings, node.name, value) {
+			result = append(result, &cssNode{nodeType: cssDeclaration, name: node.name, value: value})
+		}
+	}
+	return append(result, node)
+}
+
+func hasDeclaration(nodes []*cssNode, name, value string) bool {
+	for _, node := range nodes {
+		if node.nodeType == cssDeclaration && node.name == name && (value == "" || node.value == value) {
+			return true
+		}
+	}
+	return false
+}
+
+// prefixSelector duplicates a rule for each prefixed pseudo-element. Prefixed selectors cannot be grouped with
+// others because browsers drop a whole rule if they do not understand one of its selectors.
+func prefixSelector(node *cssNode, targets browserTargets) []*cssNode {
+	// The selectors are sorted so the rules are always output in the same order
+	var selectors []string
+	for selector := range cssSelectorPrefixes {
+		selectors = append(selectors, selector)
+	}
+	sort.Strings(selectors)
+
+	var result []*cssNode
+	for _, selector := range selectors {
+		if !strings.Contains(node.prelude, selector) {
+			continue
+		}
+		for _, prefix := range cssSelectorPrefixes[selector] {
+			if targets.olderThan(prefix.support) {
+				result = append(result, &cssNode{
+					nodeType: cssRule,
+					prelude:  strings.Replace(node.prelude, selector, prefix.replacement, -1),
+					block:    true,
+					children: node.children,
+				})
+			}
+		}
+	}
+	return append(result, node)
+}
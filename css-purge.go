@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type cssPurgeAction struct{}
+
+var purgeTokenRegex = regexp.MustCompile(`[A-Za-z0-9_:/.@-]+`)
+var purgeWordRegex = regexp.MustCompile(`[A-Za-z0-9_-]+`)
+var purgeFunctionRegex = regexp.MustCompile(`::?([a-zA-Z-]+)\(`)
+var purgeAttributeRegex = regexp.MustCompile(`\[[^\]]*\]`)
+var purgePseudoRegex = regexp.MustCompile(`::?[a-zA-Z-]+`)
+var purgeNameRegex = regexp.MustCompile(`([.#])((?:\\.|[A-Za-z0-9_-])+)`)
+var purgeTagRegex = regexp.MustCompile(`(?:^|[\s>+~(,])([a-zA-Z][a-zA-Z0-9-]*)`)
+
+// purgeAlwaysUsed are selectors that are always considered used
+var purgeAlwaysUsed = []string{"html", "body", "*", ":root"}
+
+// purgeAnyOf are the pseudo-classes that match if any of the selectors they take match
+var purgeAnyOf = []string{"is", "where", "has", "matches", "-webkit-any", "-moz-any"}
+
+func (action cssPurgeAction) Action(files []string, options map[string]interface{}) (outputFiles []string) {
+	if len(files) == 0 {
+		return files
+	}
+
+	contentGlobs := optionStrings(options, "content")
+	if len(contentGlobs) == 0 {
+		contentGlobs = []string{".html", ".js"}
+	}
+
+	buildFiles, _, err := filesInPath(config.BuildDir)
+	if err != nil {
+		errorMsg(fmt.Sprintf("Folder '%s' not found.", config.BuildDir), err)
+		return files
+	}
+
+	tokens := make(map[string]bool)
+	for _, file := range globFiles(contentGlobs, config.BuildDir, buildFiles) {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not read file '%s'", file), err)
+			continue
+		}
+		for _, token := range purgeTokenRegex.FindAllString(string(content), -1) {
+			tokens[token] = true
+		}
+		for _, token := range purgeWordRegex.FindAllString(string(content), -1) {
+			tokens[strings.ToLower(token)] = true
+			tokens[token] = true
+		}
+	}
+
+	safelist, err := parsePurgeSafelist(optionStrings(options, "safelist"))
+	if err != nil {
+		errorMsg("Invalid 'safelist' option in 'css-purge' action. Skipping task...", err)
+		return files
+	}
+
+	report := make(map[string][]string)
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not read file '%s'", file), err)
+			continue
+		}
+
+		var removed []string
+		nodes := purgeCSS(parseCSS(string(content)), tokens, safelist, &removed)
+
+		newFile := file
+		if !strings.HasPrefix(file, config.BuildDir) {
+			newFile = fmt.Sprintf("%s%s", config.BuildDir, relativePath(file))
+		}
+
+		err = os.MkdirAll(filepath.Dir(newFile), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(newFile, []byte(serializeCSSLike(nodes, string(content))), 0644)
+		}
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not write to '%s' in 'css-purge' action.", newFile), err)
+			continue
+		}
+		outputFiles = append(outputFiles, newFile)
+		report[relativePath(newFile)] = removed
+	}
+
+	if reportFile, ok := options["report"].(string); ok {
+		reportFile = fmt.Sprintf("%s%s", config.BuildDir, reportFile)
+		var data bytes.Buffer
+		encoder := json.NewEncoder(&data)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "    ")
+		encoder.Encode(report)
+
+		err = os.MkdirAll(filepath.Dir(reportFile), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(reportFile, data.Bytes(), 0644)
+		}
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not write to '%s' defined in 'css-purge' action.", reportFile), err)
+		}
+	}
+
+	return outputFiles
+}
+
+// purgeSafelist holds selector patterns that are never removed. Patterns wrapped in slashes are regular
+// expressions, all other patterns are globs.
+type purgeSafelist struct {
+	globs   []string
+	regexes []*regexp.Regexp
+}
+
+func parsePurgeSafelist(patterns []string) (purgeSafelist, error) {
+	var safelist purgeSafelist
+	for _, pattern := range patterns {
+		if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+			regex, err := regexp.Compile(pattern[1 : len(pattern)-1])
+			if err != nil {
+				return safelist, err
+			}
+			safelist.regexes = append(safelist.regexes, regex)
+			continue
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return safelist, fmt.Errorf("invalid glob '%s': %s", pattern, err)
+		}
+		safelist.globs = append(safelist.globs, pattern)
+	}
+	return safelist, nil
+}
+
+// matches checks the safelist against a whole selector and each of its class and ID names
+func (safelist purgeSafelist) matches(selector string) bool {
+	candidates := []string{selector}
+	for _, match := range purgeNameRegex.FindAllStringSubmatch(selector, -1) {
+		candidates = append(candidates, unescapeCSS(match[2]), fmt.Sprintf("%s%s", match[1], unescapeCSS(match[2])))
+	}
+
+	for _, candidate := range candidates {
+		for _, glob := range safelist.globs {
+			if matched, _ := path.Match(glob, candidate); matched {
+				return true
+			}
+		}
+		for _, regex := range safelist.regexes {
+			if regex.MatchString(candidate) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// purgeCSS removes selectors that do not match any of the tokens found in the content files. Rules without any
+// remaining selectors are removed along with any conditional at-rules left empty.
+func purgeCSS(nodes []*cssNode, tokens map[string]bool, safelist purgeSafelist, removed *[]string) []*cssNode {
+	var result []*cssNode
+	for _, node := range nodes {
+		switch {
+		case node.nodeType == cssRule:
+			var kept []string
+			for _, selector := range splitSelectors(node.prelude) {
+				if selectorUsed(selector, tokens) || safelist.matches(selector) {
+					kept = append(kept, selector)
+				} else {
+					*removed = append(*removed, selector)
+				}
+			}
+			if len(kept) == 0 {
+				continue
+			}
+			node.prelude = strings.Join(kept, ", ")
+		case node.nodeType == cssAtRule && node.block && isConditionalAtRule(node.name):
+			node.children = purgeCSS(node.children, tokens, safelist, removed)
+			if len(node.children) == 0 {
+				continue
+			}
+		}
+		result = append(result, node)
+	}
+	sort.Strings(*removed)
+	return result
+}
+
+// selectorUsed reports whether every class, ID and tag of a selector was found in the content files
+func selectorUsed(selector string, tokens map[string]bool) bool {
+	if stringInSlice(selector, purgeAlwaysUsed) {
+		return true
+	}
+
+	// Attributes and pseudo-classes cannot be checked statically
+	simplified := purgeAttributeRegex.ReplaceAllString(selector, "")
+	simplified, used := purgePseudoFunctions(simplified, tokens)
+	if !used {
+		return false
+	}
+
+	for _, match := range purgeNameRegex.FindAllStringSubmatch(simplified, -1) {
+		if !tokens[unescapeCSS(match[2])] {
+			return false
+		}
+	}
+
+	simplified = purgeNameRegex.ReplaceAllString(simplified, "")
+	simplified = purgePseudoRegex.ReplaceAllString(simplified, "")
+	for _, match := range purgeTagRegex.FindAllStringSubmatch(simplified, -1) {
+		tag := strings.ToLower(match[1])
+		if !tokens[tag] && !stringInSlice(tag, purgeAlwaysUsed) {
+			return false
+		}
+	}
+	return true
+}
+
+// purgePseudoFunctions removes the functional pseudo-classes from a selector. It reports false if none of the
+// selectors given to :is(), :where() or :has() are used. The arguments of the other functions are either not
+// selectors, like those of :nth-child() and :lang(), or cannot be checked statically, like those of :not().
+func purgePseudoFunctions(selector string, tokens map[string]bool) (string, bool) {
+	var simplified strings.Builder
+	for {
+		match := purgeFunctionRegex.FindStringSubmatchIndex(selector)
+		if match == nil {
+			simplified.WriteString(selector)
+			return simplified.String(), true
+		}
+
+		end, depth := match[1], 1
+		for ; end < len(selector); end++ {
+			if selector[end] == '(' {
+				depth++
+			} else if selector[end] == ')' {
+				if depth--; depth == 0 {
+					break
+				}
+			}
+		}
+
+		if stringInSlice(strings.ToLower(selector[match[2]:match[3]]), purgeAnyOf) {
+			used := false
+			for _, argument := range splitSelectors(selector[match[1]:minInt(end, len(selector))]) {
+				if selectorUsed(argument, tokens) {
+					used = true
+					break
+				}
+			}
+			if !used {
+				return "", false
+			}
+		}
+		simplified.WriteString(selector[:match[0]])
+		selector = selector[minInt(end+1, len(selector)):]
+	}
+}
+
+func unescapeCSS(value string) string {
+	return strings.Replace(value, "\\", "", -1)
+}
@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestSelectorUsed(t *testing.T) {
+	tokens := map[string]bool{"li": true, "p": true, "a": true, "nav": true, "menu": true, "active": true, "main": true}
+	tests := []struct {
+		selector string
+		used     bool
+	}{
+		{"li", true},
+		{"ul", false},
+		{".menu", true},
+		{".missing", false},
+		{"#main", true},
+		{"nav .menu > li", true},
+		{"nav .menu > ul", false},
+		{"a:hover", true},
+		{"p::first-line", true},
+		{"li:nth-child(odd)", true},
+		{"li:nth-of-type(2n+1)", true},
+		{"li:nth-child(2 of .missing)", true},
+		{"p:lang(en)", true},
+		{"p:dir(rtl)", true},
+		{"li:not(.missing)", true},
+		{"li:not(:is(.missing))", true},
+		{":is(.menu, .missing)", true},
+		{":is(.missing, .other)", false},
+		{":where(.missing, nav) a", true},
+		{":where(.missing) a", false},
+		{"nav:has(> .active)", true},
+		{"nav:has(.missing, .other)", false},
+		{"li:is(.active, .missing):nth-child(odd)", true},
+		{"ul:is(.active)", false},
+		{"[data-open] .menu", true},
+		{"a[href^='http']", true},
+		{"html", true},
+		{":root", true},
+		{"*", true},
+	}
+	for _, test := range tests {
+		if used := selectorUsed(test.selector, tokens); used != test.used {
+			t.Errorf("selectorUsed(%q) = %v, want %v", test.selector, used, test.used)
+		}
+	}
+}
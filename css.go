@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+type cssNodeType int
+
+const (
+	cssRule cssNodeType = iota
+	cssAtRule
+	cssDeclaration
+	cssComment
+)
+
+// cssNode is a node of a parsed stylesheet. Rules use prelude for their selector, at-rules use name and prelude,
+// declarations use name for their property and value for their value.
+type cssNode struct {
+	nodeType cssNodeType
+	name     string
+	prelude  string
+	value    string
+	block    bool
+	children []*cssNode
+}
+
+type cssParser struct {
+	content string
+	pos     int
+	depth   int
+}
+
+// parseCSS parses a stylesheet into a tree of nodes. The parser is forgiving and supports nested rules.
+func parseCSS(content string) []*cssNode {
+	parser := &cssParser{content: content}
+	return parser.parseBlock()
+}
+
+func (p *cssParser) parseBlock() []*cssNode {
+	var nodes []*cssNode
+	var buffer bytes.Buffer
+
+	for p.pos < len(p.content) {
+		c := p.content[p.pos]
+		switch {
+		case c == '/' && strings.HasPrefix(p.content[p.pos:], "/*"):
+			end := len(p.content)
+			if index := strings.Index(p.content[p.pos+2:], "*/"); index > -1 {
+				end = p.pos + index + 4
+			}
+			// Comments inside of selectors or declarations are dropped
+			if strings.TrimSpace(buffer.String()) == "" {
+				nodes = append(nodes, &cssNode{nodeType: cssComment, value: p.content[p.pos:end]})
+			}
+			p.pos = end
+		case c == '"' || c == '\'':
+			buffer.WriteString(p.readString(c))
+		case c == '(':
+			buffer.WriteString(p.readParentheses())
+		case c == '{':
+			p.pos++
+			prelude := strings.TrimSpace(buffer.String())
+			buffer.Reset()
+			node := &cssNode{nodeType: cssRule, prelude: prelude, block: true}
+			if strings.HasPrefix(prelude, "@") {
+				node.nodeType = cssAtRule
+				node.name, node.prelude = splitAtRule(prelude)
+			}
+			p.depth++
+			node.children = p.parseBlock()
+			p.depth--
+			nodes = append(nodes, node)
+		case c == '}':
+			p.pos++
+			if node := statementNode(buffer.String()); node != nil {
+				nodes = append(nodes, node)
+			}
+			buffer.Reset()
+			// An unmatched '}' at the top level is ignored so the rest of the stylesheet is still parsed
+			if p.depth > 0 {
+				return nodes
+			}
+		case c == ';':
+			p.pos++
+			if node := statementNode(buffer.String()); node != nil {
+				nodes = append(nodes, node)
+			}
+			buffer.Reset()
+		default:
+			buffer.WriteByte(c)
+			p.pos++
+		}
+	}
+
+	if node := statementNode(buffer.String()); node != nil {
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+func (p *cssParser) readString(quote byte) string {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.content) {
+		c := p.content[p.pos]
+		p.pos++
+		if c == '\\' {
+			p.pos++
+		} else if c == quote {
+			break
+		}
+	}
+	return p.content[start:minInt(p.pos, len(p.content))]
+}
+
+func (p *cssParser) readParentheses() string {
+	start := p.pos
+	depth := 0
+	for p.pos < len(p.content) {
+		c := p.content[p.pos]
+		if c == '"' || c == '\'' {
+			p.readString(c)
+			continue
+		}
+		p.pos++
+		if c == '(' {
+			depth++
+		} else if c == ')' {
+			depth--
+			if depth == 0 {
+				break
+			}
+		}
+	}
+	return p.content[start:p.pos]
+}
+
+// statementNode creates a declaration or a block-less at-rule from a statement
+func statementNode(statement string) *cssNode {
+	statement = strings.TrimSpace(statement)
+	if statement == "" {
+		return nil
+	} else if strings.HasPrefix(statement, "@") {
+		name, prelude := splitAtRule(statement)
+		return &cssNode{nodeType: cssAtRule, name: name, prelude: prelude}
+	}
+
+	index := strings.Index(statement, ":")
+	if index < 0 {
+		return nil
+	}
+	return &cssNode{nodeType: cssDeclaration, name: strings.TrimSpace(statement[:index]), value: strings.TrimSpace(statement[index+1:])}
+}
+
+func splitAtRule(statement string) (name, prelude string) {
+	statement = strings.TrimPrefix(statement, "@")
+	index := strings.IndexAny(statement, " \t\n\r(")
+	if index < 0 {
+		return statement, ""
+	}
+	return statement[:index], strings.TrimSpace(statement[index:])
+}
+
+// serializeCSS writes a tree of nodes back to a stylesheet
+func serializeCSS(nodes []*cssNode) string {
+	var buffer bytes.Buffer
+	writeCSSNodes(&buffer, nodes, 0)
+	return buffer.String()
+}
+
+// serializeCSSLike writes a tree of nodes back to a stylesheet in the style of the source it was parsed from, so
+// minified stylesheets (i.e. compressed SASS output) stay minified
+func serializeCSSLike(nodes []*cssNode, source string) string {
+	if !isCompactCSS(source) {
+		return serializeCSS(nodes)
+	}
+	var buffer bytes.Buffer
+	writeCompactCSSNodes(&buffer, nodes)
+	if buffer.Len() > 0 {
+		buffer.WriteString("\n")
+	}
+	return buffer.String()
+}
+
+// isCompactCSS reports whether a stylesheet is minified, i.e. it has fewer line breaks than blocks
+func isCompactCSS(source string) bool {
+	return strings.Count(strings.TrimSpace(source), "\n") < strings.Count(source, "}")
+}
+
+func writeCSSNodes(buffer *bytes.Buffer, nodes []*cssNode, depth int) {
+	indent := strings.Repeat("    ", depth)
+	for i, node := range nodes {
+		switch node.nodeType {
+		case cssComment:
+			fmt.Fprintf(buffer, "%s%s\n", indent, node.value)
+		case cssDeclaration:
+			fmt.Fprintf(buffer, "%s%s: %s;\n", indent, node.name, node.value)
+		case cssAtRule, cssRule:
+			header := node.prelude
+			if node.nodeType == cssAtRule {
+				header = strings.TrimSpace(fmt.Sprintf("@%s %s", node.name, node.prelude))
+			}
+			if !node.block {
+				fmt.Fprintf(buffer, "%s%s;\n", indent, header)
+				continue
+			}
+			fmt.Fprintf(buffer, "%s%s {\n", indent, header)
+			writeCSSNodes(buffer, node.children, depth+1)
+			fmt.Fprintf(buffer, "%s}\n", indent)
+			if depth == 0 && i < len(nodes)-1 {
+				buffer.WriteString("\n")
+			}
+		}
+	}
+}
+
+func writeCompactCSSNodes(buffer *bytes.Buffer, nodes []*cssNode) {
+	for i, node := range nodes {
+		// Statements are separated by semicolons, the last one of a block does not need one
+		separator := ";"
+		if i == len(nodes)-1 {
+			separator = ""
+		}
+		switch node.nodeType {
+		case cssComment:
+			buffer.WriteString(node.value)
+		case cssDeclaration:
+			fmt.Fprintf(buffer, "%s:%s%s", node.name, node.value, separator)
+		case cssAtRule, cssRule:
+			header := node.prelude
+			if node.nodeType == cssAtRule {
+				header = strings.TrimSpace(fmt.Sprintf("@%s %s", node.name, node.prelude))
+			}
+			if !node.block {
+				fmt.Fprintf(buffer, "%s%s", header, separator)
+				continue
+			}
+			fmt.Fprintf(buffer, "%s{", header)
+			writeCompactCSSNodes(buffer, node.children)
+			buffer.WriteString("}")
+		}
+	}
+}
+
+// splitSelectors splits a selector list on commas that are not inside of parentheses, brackets or strings
+func splitSelectors(selector string) []string {
+	var selectors []string
+	depth := 0
+	start := 0
+	var quote rune
+	for i, c := range selector {
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			selectors = append(selectors, strings.TrimSpace(selector[start:i]))
+			start = i + 1
+		}
+	}
+	return append(selectors, strings.TrimSpace(selector[start:]))
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// diffEntry is a file of one side of a diff. Content is nil for files listed in a build manifest.
+type diffEntry struct {
+	size    int64
+	hash    string
+	content func() ([]byte, error)
+}
+
+// maxDiffCells limits the size of the line comparison of two files
+const maxDiffCells = 25000000
+
+func diffCommand(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	targets := flags.Bool("targets", false, "Compare the resolved source files of two targets instead of two builds.")
+	stat := flags.Bool("stat", false, "Only list the added, removed and changed files without showing diffs.")
+	context := flags.Int("context", 3, "The number of lines of context in diffs.")
+	flags.Usage = func() {
+		fmt.Printf("Usage of diff:\n  web-build diff [FLAGS] <build|archive|manifest> <build|archive|manifest>\n  web-build diff -targets <target> <target>\n\n  Flags:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	var before, after map[string]diffEntry
+	var err error
+	if *targets {
+		if !loadConfig() {
+			os.Exit(1)
+		}
+		before, err = targetDiffEntries(flags.Arg(0))
+		if err == nil {
+			after, err = targetDiffEntries(flags.Arg(1))
+		}
+	} else {
+		before, err = buildDiffEntries(flags.Arg(0))
+		if err == nil {
+			after, err = buildDiffEntries(flags.Arg(1))
+		}
+	}
+	if err != nil {
+		errorMsg("Could not load files to compare.", err)
+		os.Exit(1)
+	}
+
+	printDiff(before, after, !*stat, *context)
+}
+
+// buildDiffEntries loads the files of a build directory, archive or build manifest
+func buildDiffEntries(location string) (map[string]diffEntry, error) {
+	info, err := os.Stat(location)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.ToLower(location)
+	switch {
+	case info.IsDir():
+		return directoryDiffEntries(location)
+	case strings.HasSuffix(name, ".json"):
+		return manifestDiffEntries(location)
+	case strings.HasSuffix(name, ".zip"):
+		return zipDiffEntries(location)
+	case strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz"):
+		return tarDiffEntries(location, func(r io.Reader) (io.Reader, error) {
+			return gzip.NewReader(r)
+		})
+	case strings.HasSuffix(name, ".tar.zst") || strings.HasSuffix(name, ".tzst"):
+		return tarDiffEntries(location, func(r io.Reader) (io.Reader, error) {
+			return zstd.NewReader(r)
+		})
+	}
+	return nil, fmt.Errorf("'%s' is not a directory, archive or build manifest", location)
+}
+
+func directoryDiffEntries(dir string) (map[string]diffEntry, error) {
+	files, _, err := filesInPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	dir = filepath.ToSlash(filepath.Clean(dir))
+
+	entries := make(map[string]diffEntry)
+	for _, file := range files {
+		entry, err := fileDiffEntry(file)
+		if err != nil {
+			return nil, err
+		}
+		entries[strings.TrimPrefix(file, dir)] = entry
+	}
+	return entries, nil
+}
+
+func fileDiffEntry(file string) (diffEntry, error) {
+	hash, size, err := hashFile(file)
+	if err != nil {
+		return diffEntry{}, err
+	}
+	return diffEntry{size, hash, func() ([]byte, error) {
+		return ioutil.ReadFile(file)
+	}}, nil
+}
+
+func contentDiffEntry(data []byte) diffEntry {
+	hash := sha256.Sum256(data)
+	return diffEntry{int64(len(data)), hex.EncodeToString(hash[:]), func() ([]byte, error) {
+		return data, nil
+	}}
+}
+
+func manifestDiffEntries(file string) (map[string]diffEntry, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var manifest buildManifest
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]diffEntry)
+	for _, f := range manifest.Files {
+		entries[f.Path] = diffEntry{f.Size, f.SHA256, nil}
+	}
+	return entries, nil
+}
+
+func zipDiffEntries(file string) (map[string]diffEntry, error) {
+	r, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	entries := make(map[string]diffEntry)
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		entries[fmt.Sprintf("/%s", strings.TrimPrefix(f.Name, "/"))] = contentDiffEntry(data)
+	}
+	return entries, nil
+}
+
+func tarDiffEntries(file string, decompress func(io.Reader) (io.Reader, error)) (map[string]diffEntry, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r, err := decompress(f)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]diffEntry)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		} else if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		entries[fmt.Sprintf("/%s", strings.TrimPrefix(header.Name, "/"))] = contentDiffEntry(data)
+	}
+	return entries, nil
+}
+
+// targetDiffEntries loads the source files a target resolves to through its dependencies
+func targetDiffEntries(target string) (map[string]diffEntry, error) {
+	if _, ok := config.Targets[target]; !ok {
+		return nil, &invalidTargetError{target}
+	}
+	config.Target = target
+
+	entries := make(map[string]diffEntry)
+	for _, file := range resolveTargetFiles([]string{"/**"}) {
+		entry, err := fileDiffEntry(file)
+		if err != nil {
+			return nil, err
+		}
+		entries[relativePath(file)] = entry
+	}
+	return entries, nil
+}
+
+// printDiff lists the added, removed and changed files with their size differences, followed by unified diffs
+// of the changed text files
+func printDiff(before, after map[string]diffEntry, showContent bool, context int) {
+	var paths []string
+	for path := range before {
+		paths = append(paths, path)
+	}
+	for path := range after {
+		if _, ok := before[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var changed []string
+	added, removed, unchanged := 0, 0, 0
+	for _, path := range paths {
+		a, inBefore := before[path]
+		b, inAfter := after[path]
+		switch {
+		case !inBefore:
+			added++
+			fmt.Printf("  %s %s (%s)\n", fmtGreen("+"), path, formatSizeDelta(b.size))
+		case !inAfter:
+			removed++
+			fmt.Printf("  %s %s (%s)\n", fmtRed("-"), path, formatSizeDelta(-a.size))
+		case a.hash != b.hash:
+			changed = append(changed, path)
+			fmt.Printf("  %s %s (%s)\n", fmtCyan("~"), path, formatSizeDelta(b.size-a.size))
+		default:
+			unchanged++
+		}
+	}
+	fmt.Printf("\nAdded: %d, Removed: %d, Changed: %d, Unchanged: %d\n", added, removed, len(changed), unchanged)
+
+	if !showContent {
+		return
+	}
+	for _, path := range changed {
+		a, b := before[path], after[path]
+		if a.content == nil || b.content == nil {
+			continue
+		}
+		aData, err := a.content()
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not read '%s'", path), err)
+			continue
+		}
+		bData, err := b.content()
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not read '%s'", path), err)
+			continue
+		}
+		if !isTextContent(aData) || !isTextContent(bData) {
+			continue
+		}
+		fmt.Printf("\n%s", unifiedDiff(path, splitLines(aData), splitLines(bData), context))
+	}
+}
+
+func formatSizeDelta(delta int64) string {
+	if delta > 0 {
+		return fmt.Sprintf("+%d bytes", delta)
+	}
+	return fmt.Sprintf("%d bytes", delta)
+}
+
+// isTextContent reports whether data looks like text, i.e. valid UTF-8 without NUL bytes
+func isTextContent(data []byte) bool {
+	sample := data
+	if len(sample) > 8000 {
+		sample = sample[:8000]
+	}
+	return !bytes.Contains(sample, []byte{0}) && utf8.Valid(data)
+}
+
+func splitLines(data []byte) []string {
+	lines := strings.SplitAfter(string(data), "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// unifiedDiff returns the unified diff of two files. The lines are compared with their longest common subsequence,
+// files too large to compare are only reported as different.
+func unifiedDiff(path string, a, b []string, context int) string {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "--- a%s\n+++ b%s\n", path, path)
+	if len(a)*len(b) > maxDiffCells {
+		fmt.Fprintf(&out, "Files are too large to compare.\n")
+		return out.String()
+	}
+
+	// lengths[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	type diffLine struct {
+		op   byte
+		text string
+		a, b int
+	}
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i], i, j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lengths[i+1][j] >= lengths[i][j+1]):
+			lines = append(lines, diffLine{'-', a[i], i, j})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j], i, j})
+			j++
+		}
+	}
+
+	// Group the changes into hunks with the lines of context around them
+	for start := 0; start < len(lines); {
+		if lines[start].op == ' ' {
+			start++
+			continue
+		}
+		from := start - context
+		if from < 0 {
+			from = 0
+		}
+		end := start
+		// Changes separated by at most twice the context are in the same hunk, as their context would overlap or touch
+		for k := start; k < len(lines) && k <= end+2*context+1; k++ {
+			if lines[k].op != ' ' {
+				end = k
+			}
+		}
+		to := end + context + 1
+		if to > len(lines) {
+			to = len(lines)
+		}
+
+		aCount, bCount := 0, 0
+		for _, line := range lines[from:to] {
+			if line.op != '+' {
+				aCount++
+			}
+			if line.op != '-' {
+				bCount++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%s +%s @@\n", hunkRange(lines[from].a, aCount), hunkRange(lines[from].b, bCount))
+		for _, line := range lines[from:to] {
+			text := strings.TrimSuffix(line.text, "\n")
+			switch line.op {
+			case '+':
+				text = fmtGreen(fmt.Sprintf("+%s", text))
+			case '-':
+				text = fmtRed(fmt.Sprintf("-%s", text))
+			default:
+				text = fmt.Sprintf(" %s", text)
+			}
+			fmt.Fprintf(&out, "%s\n", text)
+			if !strings.HasSuffix(line.text, "\n") {
+				out.WriteString("\\ No newline at end of file\n")
+			}
+		}
+		start = to
+	}
+	return out.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
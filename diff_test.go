@@ -0,0 +1,51 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	added := func(line string) string { return fmtGreen("+"+line) + "\n" }
+	removed := func(line string) string { return fmtRed("-"+line) + "\n" }
+	noNewline := "\\ No newline at end of file\n"
+	header := "--- a/file.txt\n+++ b/file.txt\n"
+
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		diff    string
+	}{
+		{"identical", "1\n2\n", "1\n2\n", 3, header},
+		{"changed line", "1\n2\n3\n4\n5\n", "1\n2\nx\n4\n5\n", 1,
+			header + "@@ -2,3 +2,3 @@\n 2\n" + removed("3") + added("x") + " 4\n"},
+		{"separate hunks", "1\n2\n3\n4\n5\n6\n7\n8\n9\n", "1\nx\n3\n4\n5\n6\n7\ny\n9\n", 1,
+			header + "@@ -1,3 +1,3 @@\n 1\n" + removed("2") + added("x") + " 3\n" +
+				"@@ -7,3 +7,3 @@\n 7\n" + removed("8") + added("y") + " 9\n"},
+		{"merged hunks", "1\n2\n3\n4\n", "x\n2\n3\ny\n", 1,
+			header + "@@ -1,4 +1,4 @@\n" + removed("1") + added("x") + " 2\n 3\n" + removed("4") + added("y")},
+		{"hunks too far apart to merge", "1\n2\n3\n4\n5\n", "x\n2\n3\n4\ny\n", 1,
+			header + "@@ -1,2 +1,2 @@\n" + removed("1") + added("x") + " 2\n" +
+				"@@ -4,2 +4,2 @@\n 4\n" + removed("5") + added("y")},
+		{"added to empty file", "", "x\n", 3, header + "@@ -0,0 +1,1 @@\n" + added("x")},
+		{"removed all lines", "x\ny\n", "", 3, header + "@@ -1,2 +0,0 @@\n" + removed("x") + removed("y")},
+		{"no newline at end", "1\n2", "1\n3", 3,
+			header + "@@ -1,2 +1,2 @@\n 1\n" + removed("2") + noNewline + added("3") + noNewline},
+		{"newline added at end", "1\n2", "1\n2\n", 3,
+			header + "@@ -1,2 +1,2 @@\n 1\n" + removed("2") + noNewline + added("2")},
+	}
+	for _, test := range tests {
+		diff := unifiedDiff("/file.txt", splitLines([]byte(test.a)), splitLines([]byte(test.b)), test.context)
+		if diff != test.diff {
+			t.Errorf("%s: unifiedDiff() =\n%s\nwant\n%s", test.name, diff, test.diff)
+		}
+	}
+}
+
+func TestUnifiedDiffTooLarge(t *testing.T) {
+	lines := strings.Split(strings.Repeat("x\n", maxDiffCells/100+1), "\n")
+	if diff := unifiedDiff("/file.txt", lines, lines[:100], 3); !strings.HasSuffix(diff, "Files are too large to compare.\n") {
+		t.Errorf("unifiedDiff() = %q, want files too large to compare", diff)
+	}
+}
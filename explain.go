@@ -0,0 +1,230 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func explainCommand(args []string) {
+	flags := flag.NewFlagSet("explain", flag.ExitOnError)
+	target := flags.String("target", "", "The target to explain the path for. Defaults to the target specified in 'web-build.json'.")
+	manifest := flags.String("manifest", "", "A build manifest to read the actions and source files that produced the file from.")
+	flags.Usage = func() {
+		fmt.Printf("Usage of explain:\n  web-build explain [FLAGS] <path>\n\n  The path may be relative to the build directory, a target directory or a path to a source or build file.\n\n  Flags:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(1)
+	}
+
+	if !loadConfig() {
+		os.Exit(1)
+	}
+	if *target != "" {
+		if !checkValidTarget(*target, config) {
+			errorMsg(fmt.Sprintf("The target '%s' is invalid.", *target), nil)
+			os.Exit(1)
+		}
+		config.Target = *target
+	}
+
+	relative := explainPath(flags.Arg(0))
+	fmt.Printf("Path: %s\n", fmtCyan(relative))
+	if len(config.Targets) > 0 {
+		fmt.Printf("Target: %s\n", fmtCyan(config.Target))
+	}
+
+	var manifestData *buildManifest
+	var recorded *buildManifestFile
+	if *manifest != "" {
+		manifestData, recorded = manifestEntry(*manifest, relative)
+	}
+
+	// Files that are renamed by their actions (i.e. .md to .html) are explained by the sources in the manifest
+	paths := []string{relative}
+	if _, _, ok := resolveTargetPath(relative); !ok && recorded != nil && len(recorded.Sources) > 0 {
+		paths = nil
+		for _, source := range recorded.Sources {
+			paths = append(paths, source.Path)
+		}
+	}
+	for _, path := range paths {
+		if path != relative {
+			fmt.Printf("\nSource: %s\n", fmtCyan(path))
+		}
+		source, winner, ok := resolveTargetPath(path)
+		explainTargetChain(path, winner, ok)
+		if ok {
+			explainTasks(source)
+		}
+	}
+	if manifestData != nil {
+		explainManifest(manifestData, recorded)
+	}
+}
+
+// explainPath turns a path into a path relative to the build directory or a target directory
+func explainPath(path string) string {
+	absolute, err := filepath.Abs(path)
+	if err == nil {
+		absolute = filepath.ToSlash(absolute)
+		if strings.HasPrefix(absolute, fmt.Sprintf("%s/", config.BuildDir)) || strings.HasPrefix(absolute, fmt.Sprintf("%s/", config.SrcDir)) {
+			return relativePath(absolute)
+		}
+	}
+	return fmt.Sprintf("/%s", strings.TrimPrefix(filepath.ToSlash(path), "/"))
+}
+
+// explainTargetChain prints every target in the dependency chain that has the path and which one is used
+func explainTargetChain(relative, winner string, found bool) {
+	fmt.Printf("\nTargets:\n")
+	dependencies := []string{""}
+	if len(config.Targets) > 0 {
+		dependencies = getTargetDependencies(config.Target)
+	}
+
+	for _, target := range dependencies {
+		file := fmt.Sprintf("%s%s", config.SrcDir, relative)
+		name := "(srcDir)"
+		if target != "" {
+			file = fmt.Sprintf("%s/%s%s", config.SrcDir, target, relative)
+			name = target
+		}
+
+		info, err := os.Stat(file)
+		switch {
+		case err != nil || info.IsDir():
+			fmt.Printf("  %s: not found\n", name)
+		case found && target == winner:
+			fmt.Printf("  %s: %s %s\n", fmtGreen(name), file, fmtGreen("(used)"))
+		default:
+			fmt.Printf("  %s: %s (overridden)\n", name, file)
+		}
+	}
+
+	if !found {
+		fmt.Printf("\nThe path does not exist in any target, so it can only be created by an action.\n")
+	}
+}
+
+// explainTasks prints the tasks whose globs match a source file along with the globs that included or excluded
+// it and the actions that run on it
+func explainTasks(source string) {
+	base := config.SrcDir
+	if len(config.Targets) > 0 {
+		base = strings.TrimSuffix(source, relativePath(source))
+	}
+
+	var names []string
+	for name := range config.Tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Printf("\nTasks:\n")
+	var buildTasks []string
+	for _, name := range names {
+		task := config.Tasks[name]
+		if !shouldRunForTarget(config.Target, task.Targets) {
+			continue
+		} else if len(task.Globs) == 0 {
+			buildTasks = append(buildTasks, name)
+			continue
+		}
+
+		included := false
+		var matches []string
+		for _, glob := range task.Globs {
+			exclusion := strings.HasPrefix(glob, "!")
+			if len(globFiles([]string{strings.TrimPrefix(glob, "!")}, base, []string{source})) == 0 {
+				continue
+			}
+			if exclusion && included {
+				included = false
+				matches = append(matches, fmt.Sprintf("excluded by \"%s\"", glob))
+			} else if !exclusion && !included {
+				included = true
+				matches = append(matches, fmt.Sprintf("included by \"%s\"", glob))
+			}
+		}
+		if len(matches) == 0 {
+			continue
+		}
+
+		status := fmtGreen(name)
+		if !included {
+			status = fmt.Sprintf("%s (not used)", name)
+		}
+		fmt.Printf("  %s\n", status)
+		for _, match := range matches {
+			fmt.Printf("    %s\n", match)
+		}
+		if included {
+			fmt.Printf("    actions: %s\n", strings.Join(targetActions(task), " -> "))
+		}
+	}
+
+	if len(buildTasks) > 0 {
+		fmt.Printf("\nTasks without globs, which may update the file in the build directory:\n")
+		for _, name := range buildTasks {
+			fmt.Printf("  %s\n    actions: %s\n", name, strings.Join(targetActions(config.Tasks[name]), " -> "))
+		}
+	}
+}
+
+// targetActions returns the names of the actions of a task that run for the current target
+func targetActions(task Task) []string {
+	var actions []string
+	for _, action := range task.Actions {
+		if shouldRunForTarget(config.Target, action.Targets) {
+			actions = append(actions, action.Action)
+		}
+	}
+	return actions
+}
+
+// manifestEntry reads a build manifest and returns it with its entry for the path. The entry is nil if the manifest
+// does not have the path.
+func manifestEntry(file, relative string) (*buildManifest, *buildManifestFile) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		errorMsg(fmt.Sprintf("Could not read build manifest '%s'.", file), err)
+		return nil, nil
+	}
+	var manifest buildManifest
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		errorMsg(fmt.Sprintf("Could not parse build manifest '%s'.", file), err)
+		return nil, nil
+	}
+
+	for _, entry := range manifest.Files {
+		if entry.Path == relative {
+			return &manifest, &entry
+		}
+	}
+	return &manifest, nil
+}
+
+// explainManifest prints the actions and source files recorded for the path in a build manifest
+func explainManifest(manifest *buildManifest, entry *buildManifestFile) {
+	fmt.Printf("\nBuild manifest (target %s):\n", manifest.Target)
+	if entry == nil {
+		fmt.Printf("  The path is not in the build manifest.\n")
+		return
+	}
+	fmt.Printf("  size: %d bytes\n  sha256: %s\n", entry.Size, entry.SHA256)
+	for _, step := range entry.Actions {
+		fmt.Printf("  action: %s (%s)\n", step.Action, step.Task)
+	}
+	for _, source := range entry.Sources {
+		fmt.Printf("  source: %s (%s)\n", source.Path, source.Target)
+	}
+}
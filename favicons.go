@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+)
+
+type faviconsAction struct{}
+
+type favicon struct {
+	name       string
+	size       int
+	background bool
+}
+
+var favicons = []favicon{
+	{"favicon-16x16.png", 16, false},
+	{"favicon-32x32.png", 32, false},
+	{"favicon-96x96.png", 96, false},
+	{"android-chrome-192x192.png", 192, false},
+	{"android-chrome-512x512.png", 512, false},
+	{"apple-touch-icon.png", 180, true},
+	{"apple-touch-icon-152x152.png", 152, true},
+	{"apple-touch-icon-167x167.png", 167, true},
+}
+
+var faviconICOSizes = []int{16, 32, 48}
+
+func (action faviconsAction) Action(files []string, options map[string]interface{}) (outputFiles []string) {
+	if newFile, ok := options["input"]; ok {
+		if newFile, ok := newFile.(string); ok {
+			files = resolveFiles([]string{newFile})
+		} else {
+			errorMsg("Invalid 'input' option in 'favicons' action.", nil)
+			return files
+		}
+	}
+
+	if len(files) == 0 {
+		return files
+	} else if len(files) > 1 {
+		errorMsg(fmt.Sprintf("The 'favicons' action expects one source image but received %d. Using '%s'.", len(files), files[0]), nil)
+	}
+
+	source, err := decodeImage(files[0])
+	if err != nil {
+		errorMsg("Could not read source image in 'favicons' action. Skipping task...", err)
+		return files
+	}
+
+	outputDir := fmt.Sprintf("%s%s", config.BuildDir, strings.TrimSuffix(optionString(options, "output", ""), "/"))
+	err = os.MkdirAll(outputDir, 0755)
+	if err != nil {
+		errorMsg(fmt.Sprintf("Could not create directory '%s' defined in 'favicons' action. Skipping task...", outputDir), err)
+		return files
+	}
+
+	background, hasBackground := parseHexColor(actionSetting(options, "backgroundColor", ""))
+
+	for _, icon := range favicons {
+		img := resizeImage(source, icon.size)
+		if icon.background && hasBackground {
+			img = flattenImage(img, background)
+		}
+
+		file := fmt.Sprintf("%s/%s", outputDir, icon.name)
+		if err = writeFaviconPNG(file, img); err != nil {
+			errorMsg(fmt.Sprintf("Could not write to '%s' in 'favicons' action.", file), err)
+			continue
+		}
+		outputFiles = append(outputFiles, file)
+	}
+
+	icoFile := fmt.Sprintf("%s/favicon.ico", outputDir)
+	if err = writeFaviconICO(icoFile, source, faviconICOSizes); err != nil {
+		errorMsg(fmt.Sprintf("Could not write to '%s' in 'favicons' action.", icoFile), err)
+	} else {
+		outputFiles = append(outputFiles, icoFile)
+	}
+
+	manifestFile := fmt.Sprintf("%s/manifest.webmanifest", outputDir)
+	if err = writeWebManifest(manifestFile, options); err != nil {
+		errorMsg(fmt.Sprintf("Could not write to '%s' in 'favicons' action.", manifestFile), err)
+	} else {
+		outputFiles = append(outputFiles, manifestFile)
+	}
+
+	return outputFiles
+}
+
+// resizeImage scales an image to a square of the given size, centering it if the source is not square
+func resizeImage(source image.Image, size int) image.Image {
+	bounds := source.Bounds()
+	width, height := size, size
+	if bounds.Dx() > bounds.Dy() {
+		height = size * bounds.Dy() / bounds.Dx()
+	} else if bounds.Dy() > bounds.Dx() {
+		width = size * bounds.Dx() / bounds.Dy()
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	offsetX := (size - width) / 2
+	offsetY := (size - height) / 2
+	draw.CatmullRom.Scale(img, image.Rect(offsetX, offsetY, offsetX+width, offsetY+height), source, bounds, draw.Over, nil)
+	return img
+}
+
+func flattenImage(img image.Image, background color.Color) image.Image {
+	flattened := image.NewNRGBA(img.Bounds())
+	draw.Draw(flattened, flattened.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+	draw.Draw(flattened, flattened.Bounds(), img, img.Bounds().Min, draw.Over)
+	return flattened
+}
+
+func parseHexColor(value string) (color.Color, bool) {
+	value = strings.TrimPrefix(value, "#")
+	if len(value) == 3 {
+		value = fmt.Sprintf("%c%c%c%c%c%c", value[0], value[0], value[1], value[1], value[2], value[2])
+	}
+	if len(value) != 6 {
+		return nil, false
+	}
+
+	rgb, err := strconv.ParseUint(value, 16, 32)
+	if err != nil {
+		return nil, false
+	}
+	return color.NRGBA{R: uint8(rgb >> 16), G: uint8(rgb >> 8), B: uint8(rgb), A: 0xff}, true
+}
+
+func encodeFaviconPNG(img image.Image) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	err := encoder.Encode(&buffer, img)
+	return buffer.Bytes(), err
+}
+
+func writeFaviconPNG(file string, img image.Image) error {
+	data, err := encodeFaviconPNG(img)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
+
+// writeFaviconICO writes a multi-resolution ICO file using PNG encoded images
+func writeFaviconICO(file string, source image.Image, sizes []int) error {
+	var images [][]byte
+	for _, size := range sizes {
+		data, err := encodeFaviconPNG(resizeImage(source, size))
+		if err != nil {
+			return err
+		}
+		images = append(images, data)
+	}
+
+	var buffer bytes.Buffer
+	binary.Write(&buffer, binary.LittleEndian, []uint16{0, 1, uint16(len(images))})
+
+	offset := 6 + 16*len(images)
+	for i, data := range images {
+		// A dimension of 0 represents 256 pixels
+		dimension := uint8(sizes[i] % 256)
+		buffer.Write([]byte{dimension, dimension, 0, 0})
+		binary.Write(&buffer, binary.LittleEndian, []uint16{1, 32})
+		binary.Write(&buffer, binary.LittleEndian, []uint32{uint32(len(data)), uint32(offset)})
+		offset += len(data)
+	}
+
+	for _, data := range images {
+		buffer.Write(data)
+	}
+
+	return ioutil.WriteFile(file, buffer.Bytes(), 0644)
+}
+
+func writeWebManifest(file string, options map[string]interface{}) error {
+	name := actionSetting(options, "name", config.Target)
+	manifest := map[string]interface{}{
+		"name":             name,
+		"short_name":       actionSetting(options, "shortName", name),
+		"start_url":        actionSetting(options, "startUrl", "/"),
+		"display":          actionSetting(options, "display", "standalone"),
+		"theme_color":      actionSetting(options, "themeColor", "#ffffff"),
+		"background_color": actionSetting(options, "backgroundColor", "#ffffff"),
+		"icons": []map[string]string{
+			{"src": "android-chrome-192x192.png", "sizes": "192x192", "type": "image/png"},
+			{"src": "android-chrome-512x512.png", "sizes": "512x512", "type": "image/png"},
+		},
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, data, 0644)
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type htmlInjectAction struct{}
+
+var injectMarkerRegex = regexp.MustCompile(`(?m)^([ \t]*)<!--\s*inject:(js|css)\s*-->`)
+var injectEndRegex = regexp.MustCompile(`<!--\s*endinject\s*-->`)
+
+func (action htmlInjectAction) Action(files []string, options map[string]interface{}) (outputFiles []string) {
+	if len(files) == 0 {
+		return files
+	}
+
+	urls := optionString(options, "urls", "relative")
+	if urls != "relative" && urls != "absolute" {
+		errorMsg(fmt.Sprintf("Invalid 'urls' option '%s' in 'html-inject' action. Skipping task...", urls), nil)
+		return files
+	}
+	baseURL := strings.TrimSuffix(optionString(options, "baseUrl", ""), "/")
+
+	assets := injectAssets(options)
+	attributes, _ := options["attributes"].(map[string]interface{})
+
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not read file '%s'", file), err)
+			continue
+		}
+
+		newFile := file
+		if !strings.HasPrefix(file, config.BuildDir) {
+			newFile = fmt.Sprintf("%s%s", config.BuildDir, relativePath(file))
+		}
+
+		tags := make(map[string][]string)
+		for _, asset := range assets {
+			url := fmt.Sprintf("%s%s", baseURL, strings.TrimPrefix(asset, config.BuildDir))
+			if urls == "relative" {
+				relative, err := filepath.Rel(filepath.Dir(newFile), asset)
+				if err != nil {
+					continue
+				}
+				url = filepath.ToSlash(relative)
+			}
+
+			switch strings.ToLower(filepath.Ext(asset)) {
+			case ".js", ".mjs":
+				tags["js"] = append(tags["js"], scriptTag(url, attributes))
+			case ".css":
+				tags["css"] = append(tags["css"], fmt.Sprintf("<link rel=\"stylesheet\" href=\"%s\">", html.EscapeString(url)))
+			}
+		}
+
+		updated := injectTags(content, tags)
+		err = os.MkdirAll(filepath.Dir(newFile), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(newFile, updated, 0644)
+		}
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not write to '%s' in 'html-inject' action.", newFile), err)
+			continue
+		}
+		outputFiles = append(outputFiles, newFile)
+	}
+	return outputFiles
+}
+
+// injectAssets collects the build files to inject from the outputs of tasks and globs and sorts them by the
+// order globs. Files not matching any order glob keep their original order after the ordered files.
+func injectAssets(options map[string]interface{}) []string {
+	var assets []string
+	for _, task := range optionStrings(options, "tasks") {
+		for _, file := range outputsOfTask(task) {
+			if strings.HasPrefix(file, config.BuildDir) && !stringInSlice(file, assets) {
+				assets = append(assets, file)
+			}
+		}
+	}
+
+	if globs := optionStrings(options, "files"); len(globs) > 0 {
+		buildFiles, _, err := filesInPath(config.BuildDir)
+		if err != nil {
+			errorMsg(fmt.Sprintf("Folder '%s' not found.", config.BuildDir), err)
+		}
+		for _, file := range globFiles(globs, config.BuildDir, buildFiles) {
+			if !stringInSlice(file, assets) {
+				assets = append(assets, file)
+			}
+		}
+	}
+
+	order := optionStrings(options, "order")
+	rank := func(file string) int {
+		for i, orderGlob := range order {
+			if len(globFiles([]string{orderGlob}, config.BuildDir, []string{file})) > 0 {
+				return i
+			}
+		}
+		return len(order)
+	}
+	sort.SliceStable(assets, func(i, j int) bool {
+		return rank(assets[i]) < rank(assets[j])
+	})
+	return assets
+}
+
+func scriptTag(url string, attributes map[string]interface{}) string {
+	tag := fmt.Sprintf("<script src=\"%s\"></script>", html.EscapeString(url))
+
+	var names []string
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	start := strings.Index(tag, ">")
+	open := tag[:start+1]
+	for _, name := range names {
+		switch value := attributes[name].(type) {
+		case bool:
+			if value {
+				open = setTagAttribute(open, name, "")
+			}
+		case string:
+			open = setTagAttribute(open, name, value)
+		}
+	}
+	return fmt.Sprintf("%s%s", open, tag[start+1:])
+}
+
+// injectTags inserts tags after their inject marker. If the marker is followed by an endinject marker, the
+// content between the two is replaced so the action may run on its own output.
+func injectTags(content []byte, tags map[string][]string) []byte {
+	var result bytes.Buffer
+	last := 0
+	for _, match := range injectMarkerRegex.FindAllSubmatchIndex(content, -1) {
+		indent := string(content[match[2]:match[3]])
+		kind := string(content[match[4]:match[5]])
+
+		result.Write(content[last:match[1]])
+		last = match[1]
+
+		var injected bytes.Buffer
+		for _, tag := range tags[kind] {
+			fmt.Fprintf(&injected, "\n%s%s", indent, tag)
+		}
+
+		// Replace previously injected tags up to the endinject marker, unless another marker comes first
+		rest := content[match[1]:]
+		end := injectEndRegex.FindIndex(rest)
+		next := injectMarkerRegex.FindIndex(rest)
+		if end != nil && (next == nil || end[0] < next[0]) {
+			result.Write(injected.Bytes())
+			fmt.Fprintf(&result, "\n%s", indent)
+			last = match[1] + end[0]
+			continue
+		}
+		result.Write(injected.Bytes())
+	}
+	result.Write(content[last:])
+	return result.Bytes()
+}
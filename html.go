@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var scriptTagRegex = regexp.MustCompile(`(?is)<script\b[^>]*>`)
+var linkTagRegex = regexp.MustCompile(`(?is)<link\b[^>]*>`)
+var htmlAttributeRegex = regexp.MustCompile(`([^\s"'=<>/]+)(?:\s*=\s*("[^"]*"|'[^']*'|[^\s"'=<>]+))?`)
+
+// tagAttributes parses the attributes of an HTML start tag. Attribute names are lowercase and values unescaped.
+func tagAttributes(tag string) map[string]string {
+	attributes := make(map[string]string)
+	for _, match := range htmlAttributeRegex.FindAllStringSubmatch(tagInner(tag), -1) {
+		value := match[2]
+		if len(value) > 1 && (value[0] == '"' || value[0] == '\'') {
+			value = value[1 : len(value)-1]
+		}
+		attributes[strings.ToLower(match[1])] = html.UnescapeString(value)
+	}
+	return attributes
+}
+
+// tagInner returns the attribute portion of a start tag without its name and closing bracket
+func tagInner(tag string) string {
+	inner := strings.TrimSuffix(strings.TrimSuffix(tag, ">"), "/")
+	if index := strings.IndexAny(inner, " \t\r\n"); index > -1 {
+		return inner[index:]
+	}
+	return ""
+}
+
+// setTagAttribute adds or replaces an attribute of an HTML start tag. An empty value creates a boolean attribute.
+func setTagAttribute(tag, name, value string) string {
+	tag = removeTagAttribute(tag, name)
+	attribute := name
+	if value != "" {
+		attribute = fmt.Sprintf("%s=\"%s\"", name, html.EscapeString(value))
+	}
+
+	if strings.HasSuffix(tag, "/>") {
+		before := strings.TrimRight(tag[:len(tag)-2], " \t\r\n")
+		return fmt.Sprintf("%s %s />", before, attribute)
+	}
+	before := strings.TrimRight(tag[:len(tag)-1], " \t\r\n")
+	return fmt.Sprintf("%s %s>", before, attribute)
+}
+
+func removeTagAttribute(tag, name string) string {
+	inner := tagInner(tag)
+	if inner == "" {
+		return tag
+	}
+	start := strings.Index(tag, inner)
+
+	cleaned := inner
+	matches := htmlAttributeRegex.FindAllStringSubmatchIndex(inner, -1)
+	for i := len(matches) - 1; i >= 0; i-- {
+		match := matches[i]
+		if !strings.EqualFold(inner[match[2]:match[3]], name) {
+			continue
+		}
+		// Remove the whitespace before the attribute along with it
+		from := match[0]
+		for from > 0 && strings.ContainsAny(inner[from-1:from], " \t\r\n") {
+			from--
+		}
+		cleaned = cleaned[:from] + cleaned[match[1]:]
+	}
+	return fmt.Sprintf("%s%s%s", tag[:start], cleaned, tag[start+len(inner):])
+}
+
+// isStylesheetLink reports whether a link tag references a stylesheet
+func isStylesheetLink(attributes map[string]string) bool {
+	for _, rel := range strings.Fields(strings.ToLower(attributes["rel"])) {
+		if rel == "stylesheet" {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveBuildReference resolves a URL referenced by a file in the build directory. Root relative URLs are
+// resolved against the build directory. Remote URLs cannot be resolved.
+func resolveBuildReference(file, reference string) (string, bool) {
+	reference, _ = splitURLSuffix(reference)
+	if reference == "" || isRemoteURL(reference) {
+		return "", false
+	} else if strings.HasPrefix(reference, "/") {
+		return path.Join(config.BuildDir, reference), true
+	}
+	return path.Join(path.Dir(file), reference), true
+}
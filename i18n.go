@@ -0,0 +1,263 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type i18nAction struct{}
+
+var translationMarkerRegex = regexp.MustCompile(`\{\{\s*t\s+"((?:\\.|[^"\\])*)"\s*\}\}`)
+
+func (action i18nAction) Action(files []string, options map[string]interface{}) (outputFiles []string) {
+	if len(files) == 0 {
+		return files
+	}
+
+	locales := config.Locales
+	if len(locales) == 0 {
+		errorMsg(fmt.Sprintf("No locales defined in %s for 'i18n' action. Skipping task...", configFile), nil)
+		return files
+	}
+	defaultLocale := config.DefaultLocale
+	if defaultLocale == "" {
+		defaultLocale = locales[0]
+	}
+
+	catalogPattern, ok := options["catalogs"].(string)
+	if !ok || !strings.Contains(catalogPattern, "{locale}") {
+		errorMsg("The 'catalogs' option in 'i18n' action must be a path containing '{locale}'. Skipping task...", nil)
+		return files
+	}
+
+	catalogs := make(map[string]map[string]string)
+	for _, locale := range append([]string{defaultLocale}, locales...) {
+		if _, ok := catalogs[locale]; ok {
+			continue
+		}
+		catalog, err := loadCatalog(strings.Replace(catalogPattern, "{locale}", locale, -1))
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not load translation catalog for locale '%s' in 'i18n' action. Skipping task...", locale), err)
+			return files
+		}
+		catalogs[locale] = catalog
+	}
+
+	contents := make(map[string][]byte)
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not read file '%s'", file), err)
+			continue
+		}
+		contents[file] = content
+	}
+
+	missing := make(map[string]map[string]bool)
+	for _, locale := range locales {
+		missing[locale] = make(map[string]bool)
+		for _, file := range files {
+			content, ok := contents[file]
+			if !ok {
+				continue
+			}
+
+			translated := translationMarkerRegex.ReplaceAllFunc(content, func(match []byte) []byte {
+				key := string(translationMarkerRegex.FindSubmatch(match)[1])
+				if unquoted, err := strconv.Unquote(fmt.Sprintf("\"%s\"", key)); err == nil {
+					key = unquoted
+				}
+				if value, ok := catalogs[locale][key]; ok {
+					return []byte(value)
+				}
+				missing[locale][key] = true
+				if value, ok := catalogs[defaultLocale][key]; ok {
+					return []byte(value)
+				}
+				return []byte(key)
+			})
+
+			newFile := fmt.Sprintf("%s/%s%s", config.BuildDir, locale, relativePath(file))
+			err := os.MkdirAll(filepath.Dir(newFile), 0755)
+			if err == nil {
+				err = ioutil.WriteFile(newFile, translated, 0644)
+			}
+			if err != nil {
+				errorMsg(fmt.Sprintf("Could not write to '%s' in 'i18n' action.", newFile), err)
+				continue
+			}
+			outputFiles = append(outputFiles, newFile)
+		}
+	}
+
+	reportMissingTranslations(missing, defaultLocale, optionString(options, "report", ""))
+
+	// Untranslated build files are replaced by their per-locale copies
+	if !optionBool(options, "keepInput", false) {
+		for _, file := range files {
+			if strings.HasPrefix(file, config.BuildDir) {
+				os.Remove(file)
+			}
+		}
+	}
+
+	return outputFiles
+}
+
+// loadCatalog merges the catalog of every target in the dependency tree so a target may override specific strings.
+// Catalogs without an extension are looked up as JSON, YAML and gettext PO files.
+func loadCatalog(relative string) (map[string]string, error) {
+	relatives := []string{relative}
+	if filepath.Ext(relative) == "" {
+		relatives = []string{fmt.Sprintf("%s.json", relative), fmt.Sprintf("%s.yaml", relative), fmt.Sprintf("%s.yml", relative), fmt.Sprintf("%s.po", relative)}
+	}
+
+	chain := targetFileChain(relatives...)
+	if len(chain) == 0 {
+		return nil, fmt.Errorf("catalog '%s' not found", relative)
+	}
+
+	catalog := make(map[string]string)
+	for _, file := range chain {
+		var entries map[string]string
+		var err error
+		if strings.ToLower(filepath.Ext(file)) == ".po" {
+			entries, err = readPOFile(file)
+		} else {
+			var value interface{}
+			value, err = readDataFile(file)
+			entries = make(map[string]string)
+			flattenCatalog(value, "", entries)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read catalog '%s': %s", file, err)
+		}
+		for key, value := range entries {
+			catalog[key] = value
+		}
+	}
+	return catalog, nil
+}
+
+// flattenCatalog converts nested catalog objects to dot separated keys
+func flattenCatalog(value interface{}, prefix string, entries map[string]string) {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range value {
+			if prefix != "" {
+				key = fmt.Sprintf("%s.%s", prefix, key)
+			}
+			flattenCatalog(inner, key, entries)
+		}
+	case nil:
+	default:
+		entries[prefix] = fmt.Sprint(value)
+	}
+}
+
+// readPOFile reads the msgid and msgstr pairs of a gettext PO file. Untranslated and fuzzy entries are skipped.
+func readPOFile(file string) (map[string]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entries := make(map[string]string)
+	var msgid, msgstr *bytes.Buffer
+	var current *bytes.Buffer
+	fuzzy := false
+
+	flush := func() {
+		if msgid != nil && msgstr != nil && msgid.Len() > 0 && msgstr.Len() > 0 && !fuzzy {
+			entries[msgid.String()] = msgstr.String()
+		}
+		msgid, msgstr, current = nil, nil, nil
+		fuzzy = false
+	}
+
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		// Comments and msgctxt or msgid start a new entry, even if it is not separated by a blank line
+		startsEntry := strings.HasPrefix(text, "#") || strings.HasPrefix(text, "msgctxt ") || strings.HasPrefix(text, "msgid ")
+		if startsEntry && msgstr != nil {
+			flush()
+		}
+
+		switch {
+		case text == "":
+			flush()
+		case strings.HasPrefix(text, "#,") && strings.Contains(text, "fuzzy"):
+			fuzzy = true
+		case strings.HasPrefix(text, "#"):
+		case strings.HasPrefix(text, "msgid "):
+			msgid = new(bytes.Buffer)
+			current = msgid
+			text = strings.TrimPrefix(text, "msgid ")
+		case strings.HasPrefix(text, "msgstr "):
+			msgstr = new(bytes.Buffer)
+			current = msgstr
+			text = strings.TrimPrefix(text, "msgstr ")
+		case strings.HasPrefix(text, "msgctxt ") || strings.HasPrefix(text, "msgid_plural ") || strings.HasPrefix(text, "msgstr["):
+			current = nil
+		}
+
+		if current != nil && strings.HasPrefix(text, "\"") {
+			value, err := strconv.Unquote(text)
+			if err != nil {
+				return nil, fmt.Errorf("invalid string on line %d", line)
+			}
+			current.WriteString(value)
+		}
+	}
+	flush()
+	return entries, scanner.Err()
+}
+
+func reportMissingTranslations(missing map[string]map[string]bool, defaultLocale, reportFile string) {
+	var report bytes.Buffer
+	var locales []string
+	for locale := range missing {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+
+	for _, locale := range locales {
+		var keys []string
+		for key := range missing[locale] {
+			keys = append(keys, key)
+		}
+		if len(keys) == 0 {
+			continue
+		}
+		sort.Strings(keys)
+		fmt.Fprintf(&report, "%s:\n  %s\n", locale, strings.Join(keys, "\n  "))
+	}
+
+	if report.Len() == 0 {
+		return
+	}
+
+	if reportFile == "" {
+		errorMsg(fmt.Sprintf("Missing translations (falling back to '%s'):\n%s", defaultLocale, report.String()), nil)
+		return
+	}
+
+	reportFile = fmt.Sprintf("%s%s", config.BuildDir, reportFile)
+	err := os.MkdirAll(filepath.Dir(reportFile), 0755)
+	if err == nil {
+		err = ioutil.WriteFile(reportFile, report.Bytes(), 0644)
+	}
+	if err != nil {
+		errorMsg(fmt.Sprintf("Could not write to '%s' defined in 'i18n' action.", reportFile), err)
+	}
+}
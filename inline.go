@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type inlineAction struct{}
+
+var inlineScriptRegex = regexp.MustCompile(`(?is)(<script\b[^>]*>)\s*</script\s*>`)
+var inlineMediaTagRegex = regexp.MustCompile(`(?is)<(?:img|source|audio|video|track|input|embed)\b[^>]*>`)
+var inlineJSStringRegex = regexp.MustCompile("([\"'`])([^\"'`\\s]+\\?(?:[^\"'`\\s#]*&)?inline(?:[&#][^\"'`\\s]*)?)[\"'`]")
+
+func (action inlineAction) Action(files []string, options map[string]interface{}) (outputFiles []string) {
+	// Without input files the HTML, CSS and JS files of the build directory are used
+	if len(files) == 0 {
+		buildFiles, _, err := filesInPath(config.BuildDir)
+		if err != nil {
+			errorMsg(fmt.Sprintf("Folder '%s' not found.", config.BuildDir), err)
+			return files
+		}
+		globs := optionStrings(options, "globs")
+		if len(globs) == 0 {
+			globs = []string{".html", ".css", ".js"}
+		}
+		files = globFiles(globs, config.BuildDir, buildFiles)
+	}
+
+	inliner := &assetInliner{limit: int64(optionInt(options, "limit", 4096))}
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not read file '%s'", file), err)
+			continue
+		}
+
+		newFile := file
+		if !strings.HasPrefix(file, config.BuildDir) {
+			newFile = fmt.Sprintf("%s%s", config.BuildDir, relativePath(file))
+		}
+
+		switch strings.ToLower(filepath.Ext(file)) {
+		case ".html", ".htm":
+			content = inliner.inlineHTML(content, file, newFile)
+		case ".css":
+			content = inliner.inlineCSS(content, file)
+		case ".js", ".mjs":
+			content = inliner.inlineJS(content, file)
+		}
+
+		err = os.MkdirAll(filepath.Dir(newFile), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(newFile, content, 0644)
+		}
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not write to '%s' in 'inline' action.", newFile), err)
+			continue
+		}
+		outputFiles = append(outputFiles, newFile)
+	}
+
+	if len(inliner.unresolved) > 0 {
+		sort.Strings(inliner.unresolved)
+		errorMsg(fmt.Sprintf("Could not resolve the following references marked with '?inline' in 'inline' action:\n  %s", strings.Join(inliner.unresolved, "\n  ")), nil)
+	}
+
+	return outputFiles
+}
+
+// assetInliner resolves the references to inline. References marked with '?inline' are always inlined, other
+// references are inlined if the file is not larger than the limit.
+type assetInliner struct {
+	limit      int64
+	unresolved []string
+}
+
+// resolve returns the file referenced by another file if it should be inlined
+func (inliner *assetInliner) resolve(file, reference string) (string, bool) {
+	reference = strings.TrimSpace(reference)
+	if reference == "" || isRemoteURL(reference) || strings.HasPrefix(reference, "#") {
+		return "", false
+	}
+
+	reference, suffix := splitURLSuffix(reference)
+	marked := hasInlineMarker(suffix)
+
+	var asset string
+	ok := false
+	if strings.HasPrefix(reference, "/") && strings.HasPrefix(file, config.SrcDir) {
+		asset, _, ok = resolveTargetPath(reference)
+	} else if strings.HasPrefix(reference, "/") {
+		asset = path.Join(config.BuildDir, reference)
+		info, err := os.Stat(asset)
+		ok = err == nil && !info.IsDir()
+	} else {
+		asset, ok = resolveRelativeFile(file, reference)
+	}
+
+	if !ok {
+		if marked {
+			inliner.unresolved = append(inliner.unresolved, fmt.Sprintf("%s: %s%s", relativePath(file), reference, suffix))
+		}
+		return "", false
+	} else if marked {
+		return asset, true
+	}
+
+	info, err := os.Stat(asset)
+	return asset, err == nil && inliner.limit > 0 && info.Size() <= inliner.limit
+}
+
+// hasInlineMarker reports whether the query of a URL suffix contains the 'inline' parameter
+func hasInlineMarker(suffix string) bool {
+	if !strings.HasPrefix(suffix, "?") {
+		return false
+	}
+	query := strings.SplitN(suffix[1:], "#", 2)[0]
+	for _, parameter := range strings.Split(query, "&") {
+		if parameter == "inline" || strings.HasPrefix(parameter, "inline=") {
+			return true
+		}
+	}
+	return false
+}
+
+// inlineCSS replaces the URLs of a stylesheet with data URIs
+func (inliner *assetInliner) inlineCSS(content []byte, file string) []byte {
+	return cssURLRegex.ReplaceAllFunc(content, func(match []byte) []byte {
+		submatches := cssURLRegex.FindSubmatch(match)
+		asset, ok := inliner.resolve(file, string(submatches[2]))
+		if !ok {
+			return match
+		}
+		uri, err := dataURI(asset)
+		if err != nil {
+			return match
+		}
+		return []byte(fmt.Sprintf("url(\"%s\")", uri))
+	})
+}
+
+// inlineJS replaces string literals with URLs marked with '?inline' with data URIs. Unmarked strings are left
+// alone as they cannot be told apart from other strings.
+func (inliner *assetInliner) inlineJS(content []byte, file string) []byte {
+	return inlineJSStringRegex.ReplaceAllFunc(content, func(match []byte) []byte {
+		submatches := inlineJSStringRegex.FindSubmatch(match)
+		asset, ok := inliner.resolve(file, string(submatches[2]))
+		if !ok {
+			return match
+		}
+		uri, err := dataURI(asset)
+		if err != nil {
+			return match
+		}
+		return []byte(fmt.Sprintf("%s%s%s", submatches[1], uri, submatches[1]))
+	})
+}
+
+// inlineHTML replaces external scripts and stylesheets with their content and the sources of images, media and
+// icons with data URIs
+func (inliner *assetInliner) inlineHTML(content []byte, file, newFile string) []byte {
+	updated := inlineScriptRegex.ReplaceAllStringFunc(string(content), func(element string) string {
+		tag := inlineScriptRegex.FindStringSubmatch(element)[1]
+		asset, ok := inliner.resolve(file, tagAttributes(tag)["src"])
+		if !ok {
+			return element
+		}
+		script, err := ioutil.ReadFile(asset)
+		if err != nil {
+			return element
+		}
+		script = inliner.inlineJS(script, asset)
+
+		for _, name := range []string{"src", "async", "defer", "integrity", "crossorigin"} {
+			tag = removeTagAttribute(tag, name)
+		}
+		return fmt.Sprintf("%s%s</script>", tag, strings.Replace(string(script), "</script", "<\\/script", -1))
+	})
+
+	updated = linkTagRegex.ReplaceAllStringFunc(updated, func(tag string) string {
+		attributes := tagAttributes(tag)
+		if !isStylesheetLink(attributes) {
+			if strings.Contains(strings.ToLower(attributes["rel"]), "icon") {
+				return inliner.inlineAttribute(tag, "href", file)
+			}
+			return tag
+		}
+
+		asset, ok := inliner.resolve(file, attributes["href"])
+		if !ok {
+			return tag
+		}
+		css, err := ioutil.ReadFile(asset)
+		if err != nil {
+			return tag
+		}
+		// URLs that are not inlined are made relative to the page
+		css = rewriteCSSURLs(inliner.inlineCSS(css, asset), asset, newFile, 0)
+
+		style := "<style>"
+		if media, ok := attributes["media"]; ok && media != "" && media != "all" {
+			style = setTagAttribute(style, "media", media)
+		}
+		return fmt.Sprintf("%s\n%s</style>", style, strings.Replace(string(css), "</style", "<\\/style", -1))
+	})
+
+	return []byte(inlineMediaTagRegex.ReplaceAllStringFunc(updated, func(tag string) string {
+		return inliner.inlineAttribute(inliner.inlineAttribute(tag, "src", file), "poster", file)
+	}))
+}
+
+// inlineAttribute replaces a URL attribute of a tag with a data URI
+func (inliner *assetInliner) inlineAttribute(tag, name, file string) string {
+	reference, ok := tagAttributes(tag)[name]
+	if !ok {
+		return tag
+	}
+	asset, ok := inliner.resolve(file, reference)
+	if !ok {
+		return tag
+	}
+	uri, err := dataURI(asset)
+	if err != nil {
+		return tag
+	}
+	return setTagAttribute(tag, name, uri)
+}
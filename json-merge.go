@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type jsonMergeAction struct{}
+
+func (action jsonMergeAction) Action(files []string, options map[string]interface{}) (outputFiles []string) {
+	if len(files) == 0 {
+		return files
+	}
+
+	arrays := optionString(options, "arrays", "replace")
+	if arrays != "replace" && arrays != "append" {
+		errorMsg(fmt.Sprintf("Invalid 'arrays' option '%s' in 'json-merge' action. Skipping task...", arrays), nil)
+		return files
+	}
+
+	outputFile, ok := options["output"].(string)
+	if ok {
+		paths := make(map[string]bool)
+		for _, file := range files {
+			relative := relativePath(file)
+			paths[relative[:len(relative)-len(filepath.Ext(relative))]] = true
+		}
+		if len(paths) > 1 {
+			errorMsg("The 'output' option in 'json-merge' action requires files with a single path. Skipping task...", nil)
+			return files
+		}
+	}
+
+	written := make(map[string]bool)
+	for _, file := range files {
+		relative := relativePath(file)
+		relative = relative[:len(relative)-len(filepath.Ext(relative))]
+
+		newFile := fmt.Sprintf("%s%s", config.BuildDir, outputFile)
+		if outputFile == "" {
+			newFile = fmt.Sprintf("%s%s%s", config.BuildDir, relative, ".json")
+		}
+		if written[newFile] {
+			continue
+		}
+		written[newFile] = true
+
+		// Files with the same path in JSON or YAML are merged together. Build files have no target chain.
+		chain := []string{file}
+		if strings.HasPrefix(file, config.SrcDir) {
+			chain = targetFileChain(fmt.Sprintf("%s.json", relative), fmt.Sprintf("%s.yaml", relative), fmt.Sprintf("%s.yml", relative))
+		}
+
+		var merged interface{}
+		failed := false
+		for _, chainFile := range chain {
+			value, err := readDataFile(chainFile)
+			if err != nil {
+				errorMsg(fmt.Sprintf("Could not read file '%s' in 'json-merge' action.", chainFile), err)
+				failed = true
+				break
+			}
+			merged = deepMerge(merged, value, arrays == "append")
+		}
+		if failed {
+			continue
+		}
+
+		data, err := json.MarshalIndent(merged, "", "    ")
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(newFile), 0755)
+		}
+		if err == nil {
+			err = ioutil.WriteFile(newFile, data, 0644)
+		}
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not write to '%s' in 'json-merge' action.", newFile), err)
+			continue
+		}
+		outputFiles = append(outputFiles, newFile)
+	}
+	return outputFiles
+}
+
+// readDataFile decodes a JSON or YAML file based on its extension
+func readDataFile(file string) (interface{}, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var value interface{}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(content, &value)
+	default:
+		err = json.Unmarshal(content, &value)
+	}
+	return value, err
+}
+
+// deepMerge merges override into base. Objects are merged key by key, arrays are either replaced or appended
+// and all other values are replaced.
+func deepMerge(base, override interface{}, appendArrays bool) interface{} {
+	switch overrideValue := override.(type) {
+	case map[string]interface{}:
+		baseValue, ok := base.(map[string]interface{})
+		if !ok {
+			return overrideValue
+		}
+		merged := make(map[string]interface{})
+		for key, value := range baseValue {
+			merged[key] = value
+		}
+		for key, value := range overrideValue {
+			merged[key] = deepMerge(merged[key], value, appendArrays)
+		}
+		return merged
+	case []interface{}:
+		if baseValue, ok := base.([]interface{}); ok && appendArrays {
+			return append(append([]interface{}{}, baseValue...), overrideValue...)
+		}
+		return overrideValue
+	}
+	return override
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// buildRecords tracks the source files and actions that produced each file in the build directory
+var buildRecords map[string]*buildRecord
+var buildRecordsMutex sync.Mutex
+
+type buildRecord struct {
+	sources []string
+	steps   []buildStep
+}
+
+type buildStep struct {
+	Task   string `json:"task"`
+	Action string `json:"action"`
+}
+
+type buildManifest struct {
+	Version    string              `json:"version"`
+	Target     string              `json:"target,omitempty"`
+	Profile    string              `json:"profile,omitempty"`
+	ConfigHash string              `json:"configHash"`
+	Files      []buildManifestFile `json:"files"`
+}
+
+type buildManifestFile struct {
+	Path    string                `json:"path"`
+	Size    int64                 `json:"size"`
+	SHA256  string                `json:"sha256"`
+	Actions []buildStep           `json:"actions"`
+	Sources []buildManifestSource `json:"sources"`
+}
+
+type buildManifestSource struct {
+	Path   string `json:"path"`
+	Target string `json:"target,omitempty"`
+}
+
+// recordActionOutputs records the provenance of the files an action wrote to the build directory. An output is
+// attributed to the input with the same relative path (ignoring its extension) or, if there is none, to every input.
+func recordActionOutputs(task, action string, inputs, outputs []string) {
+	buildRecordsMutex.Lock()
+	defer buildRecordsMutex.Unlock()
+
+	records := make(map[string]*buildRecord)
+	for _, output := range outputs {
+		if !strings.HasPrefix(output, config.BuildDir) {
+			continue
+		}
+
+		// Actions without inputs update files of the build directory in place
+		record := &buildRecord{}
+		if prior, ok := buildRecords[output]; ok && len(inputs) == 0 {
+			record.merge(prior)
+		}
+		for _, input := range matchingInputs(output, inputs) {
+			if prior, ok := buildRecords[input]; ok {
+				record.merge(prior)
+			} else if strings.HasPrefix(input, config.SrcDir) && !stringInSlice(input, record.sources) {
+				record.sources = append(record.sources, input)
+			}
+		}
+		record.steps = append(record.steps, buildStep{task, action})
+		records[output] = record
+	}
+
+	// Records are replaced after all outputs are attributed as actions may update their inputs in place
+	for output, record := range records {
+		buildRecords[output] = record
+	}
+}
+
+func (record *buildRecord) merge(other *buildRecord) {
+	for _, source := range other.sources {
+		if !stringInSlice(source, record.sources) {
+			record.sources = append(record.sources, source)
+		}
+	}
+	for _, step := range other.steps {
+		found := false
+		for _, existing := range record.steps {
+			found = found || existing == step
+		}
+		if !found {
+			record.steps = append(record.steps, step)
+		}
+	}
+}
+
+func matchingInputs(output string, inputs []string) []string {
+	key := strings.TrimSuffix(relativePath(output), filepath.Ext(output))
+	for _, input := range inputs {
+		if strings.TrimSuffix(relativePath(input), filepath.Ext(input)) == key {
+			return []string{input}
+		}
+	}
+	return inputs
+}
+
+// configRelativePath returns a path relative to the directory of the configuration file if possible
+func configRelativePath(path string) string {
+	if relative, err := filepath.Rel(configDir(), path); err == nil {
+		return filepath.ToSlash(relative)
+	}
+	return path
+}
+
+// writeBuildManifest writes a manifest of the files in the build directory with their hashes and provenance
+func writeBuildManifest(outputPath string) {
+	files, _, err := filesInPath(config.BuildDir)
+	if err != nil {
+		errorMsg(fmt.Sprintf("Folder '%s' not found.", config.BuildDir), err)
+		return
+	}
+
+	// The configuration that was built is hashed, after its variables are replaced and its profile is applied, so
+	// changes to the files it extends and includes or to the .env files change the hash. The directories are
+	// relative to the configuration file so the hash does not depend on where the project is.
+	effective := config
+	effective.SrcDir = configRelativePath(config.SrcDir)
+	effective.BuildDir = configRelativePath(config.BuildDir)
+	configData, err := json.Marshal(effective)
+	if err != nil {
+		errorMsg("Could not hash the configuration for the build manifest.", err)
+		return
+	}
+	configHash := sha256.Sum256(configData)
+
+	manifest := buildManifest{
+		Version:    version,
+		Target:     config.Target,
+		Profile:    config.Profile,
+		ConfigHash: hex.EncodeToString(configHash[:]),
+		Files:      []buildManifestFile{},
+	}
+
+	absolutePath, _ := filepath.Abs(outputPath)
+	sort.Strings(files)
+	for _, file := range files {
+		if file == filepath.ToSlash(absolutePath) {
+			continue
+		}
+
+		hash, size, err := hashFile(file)
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not read file '%s'", file), err)
+			continue
+		}
+
+		entry := buildManifestFile{
+			Path:    strings.TrimPrefix(file, config.BuildDir),
+			Size:    size,
+			SHA256:  hash,
+			Actions: []buildStep{},
+			Sources: []buildManifestSource{},
+		}
+		if record, ok := buildRecords[file]; ok {
+			entry.Actions = record.steps
+			sort.Strings(record.sources)
+			for _, source := range record.sources {
+				entry.Sources = append(entry.Sources, buildManifestSource{relativePath(source), sourceTarget(source)})
+			}
+		}
+		manifest.Files = append(manifest.Files, entry)
+	}
+
+	data, err := json.MarshalIndent(manifest, "", "    ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(outputPath), 0755)
+	}
+	if err == nil {
+		err = ioutil.WriteFile(outputPath, append(data, '\n'), 0644)
+	}
+	if err != nil {
+		errorMsg(fmt.Sprintf("Could not write build manifest '%s'.", outputPath), err)
+		return
+	}
+	fmt.Printf("Created build manifest '%s'.\n\n", outputPath)
+}
+
+// sourceTarget returns the target a source file belongs to
+func sourceTarget(file string) string {
+	regex, err := targetPathRegex()
+	if err != nil || len(config.Targets) == 0 {
+		return ""
+	}
+	if match := regex.FindStringSubmatch(file); len(match) > 1 {
+		return match[1]
+	}
+	return ""
+}
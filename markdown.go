@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	meta "github.com/yuin/goldmark-meta"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+type markdownAction struct{}
+
+// markdownPage is the data passed to layout templates
+type markdownPage struct {
+	Title   string
+	Content template.HTML
+	Meta    map[string]interface{}
+	Path    string
+	Target  string
+}
+
+func (action markdownAction) Action(files []string, options map[string]interface{}) (outputFiles []string) {
+	if len(files) == 0 {
+		return files
+	}
+
+	outputDir, ok := options["output"]
+	if !ok {
+		outputDir = config.BuildDir
+	} else {
+		outputDir = fmt.Sprintf("%s%s", config.BuildDir, outputDir)
+	}
+
+	markdown := goldmark.New(
+		goldmark.WithExtensions(extension.GFM, meta.Meta),
+		goldmark.WithParserOptions(parser.WithAutoHeadingID()),
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+	layouts := make(map[string]*template.Template)
+
+	for _, file := range files {
+		source, err := ioutil.ReadFile(file)
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not read file '%s'", file), err)
+			continue
+		}
+
+		var content bytes.Buffer
+		context := parser.NewContext()
+		if err = markdown.Convert(source, &content, parser.WithContext(context)); err != nil {
+			errorMsg(fmt.Sprintf("Could not convert file '%s' in 'markdown' action.", file), err)
+			continue
+		}
+		frontMatter := meta.Get(context)
+
+		relative := relativePath(file)
+		relative = fmt.Sprintf("%s%s", relative[:len(relative)-len(filepath.Ext(relative))], ".html")
+		newFile := fmt.Sprintf("%s%s", outputDir, relative)
+
+		layout := optionString(options, "layout", "")
+		if value, ok := frontMatter["layout"].(string); ok {
+			layout = value
+		}
+
+		output := content.Bytes()
+		if layout != "" {
+			tmpl, err := markdownLayout(layout, layouts)
+			if err != nil {
+				errorMsg(fmt.Sprintf("Could not load layout '%s' for '%s' in 'markdown' action.", layout, file), err)
+				continue
+			}
+
+			title, ok := frontMatter["title"].(string)
+			if !ok {
+				title = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+			}
+
+			var page bytes.Buffer
+			err = tmpl.Execute(&page, markdownPage{
+				Title:   title,
+				Content: template.HTML(content.String()),
+				Meta:    frontMatter,
+				Path:    relative,
+				Target:  config.Target,
+			})
+			if err != nil {
+				errorMsg(fmt.Sprintf("Could not render layout '%s' for '%s' in 'markdown' action.", layout, file), err)
+				continue
+			}
+			output = page.Bytes()
+		}
+
+		err = os.MkdirAll(filepath.Dir(newFile), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(newFile, output, 0644)
+		}
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not write to '%s' in 'markdown' action.", newFile), err)
+			continue
+		}
+		outputFiles = append(outputFiles, newFile)
+	}
+	return outputFiles
+}
+
+// markdownLayout loads a layout template relative to the source directory, resolved through the build targets
+func markdownLayout(layout string, layouts map[string]*template.Template) (*template.Template, error) {
+	if tmpl, ok := layouts[layout]; ok {
+		return tmpl, nil
+	}
+
+	file, _, ok := resolveTargetPath(layout)
+	if !ok {
+		return nil, fmt.Errorf("layout file '%s' not found", layout)
+	}
+
+	tmpl, err := template.ParseFiles(file)
+	if err != nil {
+		return nil, err
+	}
+	layouts[layout] = tmpl
+	return tmpl, nil
+}
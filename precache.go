@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type precacheManifestAction struct{}
+
+type precacheEntry struct {
+	URL      string `json:"url"`
+	Revision string `json:"revision"`
+	Size     int64  `json:"size"`
+}
+
+func (action precacheManifestAction) Action(files []string, options map[string]interface{}) (outputFiles []string) {
+	buildFiles, _, err := filesInPath(config.BuildDir)
+	if err != nil {
+		errorMsg(fmt.Sprintf("Folder '%s' not found.", config.BuildDir), err)
+		return files
+	}
+
+	globs := optionStrings(options, "globs")
+	if len(globs) == 0 {
+		globs = []string{".html", ".js", ".css"}
+	}
+
+	outputFile := fmt.Sprintf("%s%s", config.BuildDir, optionString(options, "output", "/precache-manifest.json"))
+	swSrc := optionString(options, "swSrc", "")
+	swDest := fmt.Sprintf("%s%s", config.BuildDir, optionString(options, "swDest", swSrc))
+	prefix := optionString(options, "prefix", "/")
+
+	cached := globFiles(globs, config.BuildDir, buildFiles)
+	sort.Strings(cached)
+
+	var entries []precacheEntry
+	for _, file := range cached {
+		// The manifest and service worker cannot list themselves
+		if file == outputFile || (swSrc != "" && file == swDest) {
+			continue
+		}
+
+		revision, size, err := hashFile(file)
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not read file '%s'", file), err)
+			continue
+		}
+		entries = append(entries, precacheEntry{
+			URL:      fmt.Sprintf("%s%s", prefix, strings.TrimPrefix(file, fmt.Sprintf("%s/", config.BuildDir))),
+			Revision: revision[:32],
+			Size:     size,
+		})
+	}
+
+	manifest, err := json.MarshalIndent(entries, "", "    ")
+	if err != nil {
+		errorMsg("Could not encode precache manifest.", err)
+		return files
+	}
+	if entries == nil {
+		manifest = []byte("[]")
+	}
+
+	if swSrc != "" {
+		if err = injectPrecacheManifest(swSrc, swDest, optionString(options, "placeholder", "self.__WB_MANIFEST"), manifest); err != nil {
+			errorMsg(fmt.Sprintf("Could not inject precache manifest into '%s'.", swSrc), err)
+		} else {
+			outputFiles = append(outputFiles, swDest)
+		}
+		if _, ok := options["output"]; !ok {
+			return outputFiles
+		}
+	}
+
+	content := manifest
+	if filepath.Ext(outputFile) == ".js" {
+		content = []byte(fmt.Sprintf("self.__precacheManifest = %s;\n", manifest))
+	}
+
+	err = os.MkdirAll(filepath.Dir(outputFile), 0755)
+	if err == nil {
+		err = ioutil.WriteFile(outputFile, content, 0644)
+	}
+	if err != nil {
+		errorMsg(fmt.Sprintf("Could not write to '%s' in 'precache-manifest' action.", outputFile), err)
+		return outputFiles
+	}
+	return append(outputFiles, outputFile)
+}
+
+// injectPrecacheManifest replaces the placeholder in a service worker with the manifest. The service worker is
+// read from the build directory if it has already been built, otherwise it is resolved through the build targets.
+func injectPrecacheManifest(swSrc, swDest, placeholder string, manifest []byte) error {
+	source := fmt.Sprintf("%s%s", config.BuildDir, swSrc)
+	if _, err := os.Stat(source); err != nil {
+		var ok bool
+		if source, _, ok = resolveTargetPath(swSrc); !ok {
+			return fmt.Errorf("service worker '%s' not found", swSrc)
+		}
+	}
+
+	content, err := ioutil.ReadFile(source)
+	if err != nil {
+		return err
+	} else if !bytes.Contains(content, []byte(placeholder)) {
+		return fmt.Errorf("placeholder '%s' not found in '%s'", placeholder, source)
+	}
+
+	content = bytes.Replace(content, []byte(placeholder), manifest, -1)
+	err = os.MkdirAll(filepath.Dir(swDest), 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(swDest, content, 0644)
+}
+
+// hashFile returns the hex encoded SHA-256 digest and size of a file
+func hashFile(file string) (string, int64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
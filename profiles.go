@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Profile defines the struct for a build profile, i.e. development or production
+type Profile struct {
+	Variables map[string]string
+	Skip      []string
+	Options   map[string]map[string]interface{}
+}
+
+// selectedProfile returns the profile given with -profile or the profile in the configuration data
+func selectedProfile(data map[string]interface{}) string {
+	if argProfile != "" {
+		return argProfile
+	}
+	profile, _ := data["profile"].(string)
+	return profile
+}
+
+// profileVariables returns the variables of a profile in the configuration data
+func profileVariables(data map[string]interface{}, profile string) (map[string]string, error) {
+	variables := make(map[string]string)
+	profiles, _ := data["profiles"].(map[string]interface{})
+	if _, ok := profiles[profile]; profile != "" && !ok {
+		return nil, fmt.Errorf("profile '%s' is not defined", profile)
+	}
+	definition, _ := profiles[profile].(map[string]interface{})
+	values, _ := definition["variables"].(map[string]interface{})
+	for name, value := range values {
+		if s, ok := value.(string); ok {
+			variables[name] = s
+		} else if value != nil {
+			variables[name] = fmt.Sprint(value)
+		}
+	}
+	return variables, nil
+}
+
+// checkValidProfiles checks that the selected profile and the profiles and actions referenced by the configuration exist
+func checkValidProfiles(c Config) error {
+	if _, ok := c.Profiles[c.Profile]; c.Profile != "" && !ok {
+		return fmt.Errorf("profile '%s' is not defined", c.Profile)
+	}
+
+	for profileName, profile := range c.Profiles {
+		for _, action := range profile.Skip {
+			if !stringInSlice(action, validActions) {
+				return fmt.Errorf("invalid action '%s' in 'skip' of profile '%s'", action, profileName)
+			}
+		}
+		for action := range profile.Options {
+			if !stringInSlice(action, validActions) {
+				return fmt.Errorf("invalid action '%s' in 'options' of profile '%s'", action, profileName)
+			}
+		}
+	}
+
+	for taskName, task := range c.Tasks {
+		for _, profile := range task.Profiles {
+			if _, ok := c.Profiles[profile]; !ok {
+				return fmt.Errorf("task '%s' runs for undefined profile '%s'", taskName, profile)
+			}
+		}
+		for _, action := range task.Actions {
+			for _, profile := range action.Profiles {
+				if _, ok := c.Profiles[profile]; !ok {
+					return fmt.Errorf("action '%s' in task '%s' runs for undefined profile '%s'", action.Action, taskName, profile)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// applyProfile removes the tasks and actions that do not run for the profile of the configuration and merges the
+// options of the profile into the options of its actions
+func applyProfile(c *Config) {
+	profile := c.Profiles[c.Profile]
+
+	for name, task := range c.Tasks {
+		if !shouldRunForProfile(c.Profile, task.Profiles) {
+			delete(c.Tasks, name)
+			continue
+		}
+
+		var actions []Action
+		for _, action := range task.Actions {
+			if !shouldRunForProfile(c.Profile, action.Profiles) || stringInSlice(action.Action, profile.Skip) {
+				continue
+			}
+			if overrides, ok := profile.Options[action.Action]; ok {
+				options := make(map[string]interface{})
+				mergeConfigData(options, action.Options)
+				mergeConfigData(options, overrides)
+				action.Options = options
+			}
+			actions = append(actions, action)
+		}
+		task.Actions = actions
+		c.Tasks[name] = task
+	}
+}
+
+// shouldRunForProfile reports whether a task or action with the given profiles runs for the current profile
+func shouldRunForProfile(currentProfile string, profiles []string) bool {
+	return len(profiles) == 0 || stringInSlice(currentProfile, profiles)
+}
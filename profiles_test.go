@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestApplyProfileOptions(t *testing.T) {
+	dir := t.TempDir()
+	base := `{
+		"tasks": {"Files": {"globs": [".html"], "actions": [{"action": "sitemap", "options": {"output": "/o/sitemap.xml", "robots": true}}]}},
+		"profiles": {"prod": {"options": {"sitemap": {"output": null}}}}
+	}`
+	if err := ioutil.WriteFile(filepath.Join(dir, "base.json"), []byte(base), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		config  string
+		profile string
+		options map[string]interface{}
+	}{
+		{"without profile", `{"extends": "base.json"}`, "", map[string]interface{}{"output": "/o/sitemap.xml", "robots": true}},
+		{"null removes an option", `{"extends": "base.json"}`, "prod", map[string]interface{}{"robots": true}},
+		{"null in the extending file", `{"extends": "base.json", "profiles": {"prod": {"options": {"sitemap": {"robots": null}}}}}`, "prod", map[string]interface{}{}},
+		{"option replaces null", `{"extends": "base.json", "profiles": {"prod": {"options": {"sitemap": {"output": "/p.xml"}}}}}`, "prod", map[string]interface{}{"output": "/p.xml", "robots": true}},
+	}
+	for _, test := range tests {
+		file := filepath.Join(dir, "web-build.json")
+		if err := ioutil.WriteFile(file, []byte(test.config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		data, err := readConfigData(file, nil)
+		if err != nil {
+			t.Errorf("%s: readConfigData() error = %s", test.name, err)
+			continue
+		}
+
+		var c Config
+		content, _ := json.Marshal(data)
+		if err = json.Unmarshal(content, &c); err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+		c.Profile = test.profile
+		applyProfile(&c)
+		if options := c.Tasks["Files"].Actions[0].Options; !reflect.DeepEqual(options, test.options) {
+			t.Errorf("%s: options = %v, want %v", test.name, options, test.options)
+		}
+	}
+}
@@ -0,0 +1,582 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// schemaID is the location the JSON Schema of the configuration is published at. The schema is written to
+// web-build.schema.json with 'web-build schema -output web-build.schema.json'.
+const schemaID = "https://raw.githubusercontent.com/ryanmitchener/web-build/master/web-build.schema.json"
+
+// jsonSchema is the subset of JSON Schema used to describe and validate the configuration
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	ID                   string                 `json:"$id,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 interface{}            `json:"type,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Const                interface{}            `json:"const,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AllOf                []*jsonSchema          `json:"allOf,omitempty"`
+	If                   *jsonSchema            `json:"if,omitempty"`
+	Then                 *jsonSchema            `json:"then,omitempty"`
+
+	// caseInsensitive matches keys to properties regardless of case, like encoding/json does for struct fields
+	caseInsensitive bool
+}
+
+// schemaProblem is a value of the configuration that does not match the schema
+type schemaProblem struct {
+	path    string
+	message string
+}
+
+func stringSchema(description string) *jsonSchema {
+	return &jsonSchema{Description: description, Type: "string"}
+}
+
+func integerSchema(description string) *jsonSchema {
+	return &jsonSchema{Description: description, Type: "integer"}
+}
+
+func booleanSchema(description string) *jsonSchema {
+	return &jsonSchema{Description: description, Type: "boolean"}
+}
+
+func objectSchema(description string) *jsonSchema {
+	return &jsonSchema{Description: description, Type: "object"}
+}
+
+// stringListSchema describes a string or a list of strings, as read by optionStrings
+func stringListSchema(description string) *jsonSchema {
+	return &jsonSchema{Description: description, Type: []string{"string", "array"}, Items: &jsonSchema{Type: "string"}}
+}
+
+func enumSchema(description string, values ...interface{}) *jsonSchema {
+	return &jsonSchema{Description: description, Enum: values}
+}
+
+// actionSchemas describe the options of each action
+var actionSchemas = map[string]*jsonSchema{
+	"collate": {Properties: map[string]*jsonSchema{
+		"output": stringSchema("The base output directory relative to the build directory."),
+	}},
+	"concat": {Properties: map[string]*jsonSchema{
+		"separator": stringSchema("The separator to use in between files."),
+		"output":    stringSchema("The file to create relative to the build directory."),
+	}, Required: []string{"output"}},
+	"js-minify": {Properties: map[string]*jsonSchema{
+		"input":  stringSchema("A file to minify instead of the files passed from the previous action."),
+		"output": stringSchema("The file to create relative to the build directory if there is a single input file."),
+	}},
+	"sass": {Properties: map[string]*jsonSchema{
+		"output": stringSchema("The base output directory relative to the build directory."),
+	}},
+	"shell": {Properties: map[string]*jsonSchema{
+		"command": stringSchema("The command to run. {FILE} runs the command for every file, {FILES} is replaced with all files."),
+	}, Required: []string{"command"}},
+	"png-sprite": {Properties: map[string]*jsonSchema{
+		"output":  stringSchema("The sprite sheet to create relative to the build directory."),
+		"css":     stringSchema("The stylesheet to create relative to the build directory."),
+		"padding": integerSchema("The number of pixels between images."),
+		"prefix":  stringSchema("The class name prefix."),
+		"url":     stringSchema("The sprite sheet URL used in the stylesheet."),
+		"retina":  booleanSchema("Pack images named [name]@2x.[ext] into an @2x sprite sheet."),
+	}, Required: []string{"output"}},
+	"favicons": {Properties: map[string]*jsonSchema{
+		"input":           stringSchema("A glob for the source image instead of the files passed from the previous action."),
+		"output":          stringSchema("The directory to write the icons to relative to the build directory."),
+		"name":            stringSchema("The name in the web app manifest."),
+		"shortName":       stringSchema("The short name in the web app manifest."),
+		"startUrl":        stringSchema("The start URL in the web app manifest."),
+		"display":         stringSchema("The display mode in the web app manifest."),
+		"themeColor":      stringSchema("The theme color in the web app manifest."),
+		"backgroundColor": stringSchema("The background color in the web app manifest and of Apple touch icons."),
+	}},
+	"bundle": {Properties: map[string]*jsonSchema{
+		"output":      stringSchema("The file to create relative to the build directory for a single entry point."),
+		"outdir":      stringSchema("The directory to write the entry points to relative to the build directory."),
+		"format":      enumSchema("The output format.", "iife", "esm", "cjs"),
+		"minify":      booleanSchema("Minify the output."),
+		"sourcemap":   enumSchema("Write source maps.", true, false, "linked", "inline", "external", "both"),
+		"define":      objectSchema("Global identifiers to replace with constant expressions."),
+		"target":      stringSchema("The JavaScript version to output, i.e. es2017."),
+		"jsx":         enumSchema("How JSX is transformed.", "transform", "preserve", "automatic"),
+		"jsxFactory":  stringSchema("The function called for JSX elements."),
+		"jsxFragment": stringSchema("The function called for JSX fragments."),
+		"globalName":  stringSchema("The global variable of the exports for the iife format."),
+		"external":    stringListSchema("Imports to exclude from the bundle."),
+		"splitting":   booleanSchema("Split code shared between entry points into chunks."),
+	}},
+	"css-bundle": {Properties: map[string]*jsonSchema{
+		"output":      stringSchema("The file to concatenate the bundled files into relative to the build directory."),
+		"inlineLimit": integerSchema("The size in bytes up to which referenced files are inlined as data URIs."),
+	}},
+	"css-prefix": {Properties: map[string]*jsonSchema{
+		"browsers": stringListSchema("Browserslist queries overriding the top-level browsers property."),
+	}},
+	"css-purge": {Properties: map[string]*jsonSchema{
+		"content":  stringListSchema("Globs relative to the build directory for the files to scan."),
+		"safelist": stringListSchema("Selectors, class names or IDs that are never removed."),
+		"report":   stringSchema("A JSON file relative to the build directory to write the removed selectors to."),
+	}},
+	"markdown": {Properties: map[string]*jsonSchema{
+		"output": stringSchema("The base output directory relative to the build directory."),
+		"layout": stringSchema("The path of an HTML layout template relative to the target directory."),
+	}},
+	"json-merge": {Properties: map[string]*jsonSchema{
+		"arrays": enumSchema("Whether arrays are replaced or appended.", "replace", "append"),
+		"output": stringSchema("The file to create relative to the build directory."),
+	}},
+	"i18n": {Properties: map[string]*jsonSchema{
+		"catalogs":  stringSchema("The path of the translation catalogs relative to the target directory with a {locale} placeholder."),
+		"report":    stringSchema("A file relative to the build directory to write missing translations to."),
+		"keepInput": booleanSchema("Keep input files of the build directory."),
+	}, Required: []string{"catalogs"}},
+	"sitemap": {Properties: map[string]*jsonSchema{
+		"baseUrl":  stringSchema("The URL the build directory is served from. Falls back to the baseUrl target setting."),
+		"include":  stringListSchema("Globs relative to the build directory for the pages to list."),
+		"exclude":  stringListSchema("Globs relative to the build directory for the pages not to list."),
+		"output":   stringSchema("The sitemap file relative to the build directory."),
+		"robots":   booleanSchema("Write a robots.txt."),
+		"disallow": stringListSchema("Paths to disallow in robots.txt."),
+	}},
+	"precache-manifest": {Properties: map[string]*jsonSchema{
+		"globs":       stringListSchema("Globs relative to the build directory for the files to list."),
+		"output":      stringSchema("The manifest file relative to the build directory."),
+		"prefix":      stringSchema("The prefix of the file URLs."),
+		"swSrc":       stringSchema("A service worker to inject the manifest into."),
+		"swDest":      stringSchema("The file to write the service worker to."),
+		"placeholder": stringSchema("The placeholder in the service worker to replace with the manifest."),
+	}},
+	"sri": {Properties: map[string]*jsonSchema{
+		"html":        stringListSchema("Globs relative to the build directory for the HTML files to update without input files."),
+		"crossorigin": stringSchema("The value of the crossorigin attribute."),
+	}},
+	"html-inject": {Properties: map[string]*jsonSchema{
+		"tasks":      stringListSchema("Tasks whose output files are injected."),
+		"files":      stringListSchema("Globs relative to the build directory for additional files to inject."),
+		"order":      stringListSchema("Globs in the order files matching them are injected."),
+		"attributes": objectSchema("Attributes to add to the tags by file extension."),
+		"urls":       enumSchema("Whether URLs are relative to the HTML file or absolute.", "relative", "absolute"),
+		"baseUrl":    stringSchema("The URL prepended to absolute URLs."),
+	}},
+	"critical-css": {Properties: map[string]*jsonSchema{
+		"html":    stringListSchema("Globs relative to the build directory for the HTML files to update without input files."),
+		"maxSize": integerSchema("The maximum size in bytes of the inlined CSS."),
+	}},
+	"inline": {Properties: map[string]*jsonSchema{
+		"globs": stringListSchema("Globs relative to the build directory for the files to update without input files."),
+		"limit": integerSchema("The size in bytes up to which referenced files are inlined."),
+	}},
+}
+
+// schemaDescriptions describe the properties of the configuration by struct and field name
+var schemaDescriptions = map[string]string{
+	"Config.TemplateVersion": "The version of the template.",
+	"Config.SrcDir":          "The directory of the source files, relative to the configuration file.",
+	"Config.BuildDir":        "The directory the source files are built to, relative to the configuration file.",
+	"Config.Tasks":           "The tasks to run.",
+	"Config.Targets":         "The targets with their dependencies.",
+	"Config.Target":          "The target to build.",
+	"Config.Browsers":        "Browserslist queries for the browsers the project supports.",
+	"Config.Locales":         "The locales to build with the i18n action.",
+	"Config.DefaultLocale":   "The locale to fall back to for missing translations.",
+	"Config.Version":         "The version of the project.",
+	"Config.Profile":         "The profile to build with.",
+	"Config.Profiles":        "The build profiles.",
+	"Target.Dependency":      "The target this target depends on.",
+	"Target.Settings":        "Settings that actions fall back to.",
+	"Task.Actions":           "The actions to run on the files of the task.",
+	"Task.Targets":           "The targets the task runs for.",
+	"Task.Globs":             "Globs for the files of the task.",
+	"Task.After":             "The tasks this task runs after. \"*\" stands for every other task.",
+	"Task.Profiles":          "The profiles the task runs for.",
+	"Action.Action":          "The name of the action.",
+	"Action.Targets":         "The targets the action runs for.",
+	"Action.Profiles":        "The profiles the action runs for.",
+	"Action.Options":         "The options of the action.",
+	"Profile.Variables":      "Variables for ${VAR} in the configuration.",
+	"Profile.Skip":           "The names of actions that do not run.",
+	"Profile.Options":        "Options by action name that are merged into the options of the actions.",
+}
+
+func schemaCommand(args []string) {
+	flags := flag.NewFlagSet("schema", flag.ExitOnError)
+	output := flags.String("output", "", "The file to write the schema to. By default the schema is printed.")
+	flags.Usage = func() {
+		fmt.Printf("Usage of schema:\n  web-build schema [FLAGS]\n\n  Flags:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	data, err := json.MarshalIndent(configSchema(), "", "    ")
+	if err != nil {
+		errorMsg("Could not generate the schema.", err)
+		os.Exit(1)
+	}
+	data = append(data, '\n')
+
+	if *output == "" {
+		fmt.Print(string(data))
+		return
+	}
+	if err = ioutil.WriteFile(*output, data, 0644); err != nil {
+		errorMsg(fmt.Sprintf("Could not write to '%s'.", *output), err)
+		os.Exit(1)
+	}
+}
+
+// configSchema returns the JSON Schema of the configuration, generated from the Config struct and the options of
+// each action
+func configSchema() *jsonSchema {
+	schema := reflectSchema(reflect.TypeOf(Config{}))
+	schema.Schema = "http://json-schema.org/draft-07/schema#"
+	schema.ID = schemaID
+	schema.Title = "web-build configuration"
+	schema.Properties["$schema"] = stringSchema("The JSON Schema of the configuration.")
+	schema.Properties["extends"] = nullable(stringListSchema("Configuration files to merge this configuration over."))
+	schema.Properties["include"] = nullable(stringListSchema("Configuration files or globs to merge into this configuration."))
+
+	// Actions are limited to the valid actions and their options are described by action
+	action := schema.Properties["tasks"].AdditionalProperties.(*jsonSchema).Properties["actions"].Items
+	action.Properties["action"] = enumSchema(schemaDescriptions["Action.Action"], stringsToInterfaces(validActions)...)
+	action.Required = []string{"action"}
+	profile := schema.Properties["profiles"].AdditionalProperties.(*jsonSchema)
+	profile.Properties["skip"].Items = enumSchema("", stringsToInterfaces(validActions)...)
+	profileOptions := &jsonSchema{Description: schemaDescriptions["Profile.Options"], Type: []string{"object", "null"}, Properties: map[string]*jsonSchema{}, AdditionalProperties: false}
+	profile.Properties["options"] = profileOptions
+
+	for _, name := range validActions {
+		options := actionOptionsSchema(name, false)
+		then := &jsonSchema{Properties: map[string]*jsonSchema{"options": options}, caseInsensitive: true}
+		if len(options.Required) > 0 {
+			then.Required = []string{"options"}
+		}
+		action.AllOf = append(action.AllOf, &jsonSchema{
+			If:   &jsonSchema{Properties: map[string]*jsonSchema{"action": {Const: name}}, Required: []string{"action"}, caseInsensitive: true},
+			Then: then,
+		})
+		profileOptions.Properties[name] = actionOptionsSchema(name, true)
+	}
+	return schema
+}
+
+// actionOptionsSchema returns the schema of the options of an action. Options that are not required may be null
+// so they can be removed when configurations are merged. Options of profiles override the options of actions, so
+// none of them are required.
+func actionOptionsSchema(name string, override bool) *jsonSchema {
+	schema := &jsonSchema{Type: []string{"object", "null"}, Properties: map[string]*jsonSchema{}, AdditionalProperties: false}
+	options, ok := actionSchemas[name]
+	if !ok {
+		schema.AdditionalProperties = nil
+		return schema
+	}
+	if !override && len(options.Required) > 0 {
+		schema.Type = "object"
+		schema.Required = options.Required
+	}
+	for option, optionSchema := range options.Properties {
+		if stringInSlice(option, schema.Required) {
+			schema.Properties[option] = optionSchema
+		} else {
+			schema.Properties[option] = nullable(optionSchema)
+		}
+	}
+	return schema
+}
+
+// reflectSchema returns the schema of a configuration type. Properties are named like the struct fields starting
+// with a lower case letter and may be null so they can be removed when configurations are merged.
+func reflectSchema(t reflect.Type) *jsonSchema {
+	switch t.Kind() {
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &jsonSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: "number"}
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Slice:
+		return &jsonSchema{Type: "array", Items: reflectSchema(t.Elem())}
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: nullable(reflectSchema(t.Elem()))}
+	case reflect.Struct:
+		schema := &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{}, AdditionalProperties: false, caseInsensitive: true}
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			property := nullable(reflectSchema(field.Type))
+			property.Description = schemaDescriptions[fmt.Sprintf("%s.%s", t.Name(), field.Name)]
+			schema.Properties[lowerFirst(field.Name)] = property
+		}
+		return schema
+	}
+	return &jsonSchema{}
+}
+
+// nullable returns a copy of a schema that also allows null
+func nullable(schema *jsonSchema) *jsonSchema {
+	copied := *schema
+	switch types := schema.Type.(type) {
+	case string:
+		copied.Type = []string{types, "null"}
+	case []string:
+		copied.Type = append(append([]string{}, types...), "null")
+	}
+	if copied.Enum != nil {
+		copied.Enum = append(append([]interface{}{}, copied.Enum...), nil)
+	}
+	return &copied
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return string(unicode.ToLower(rune(s[0]))) + s[1:]
+}
+
+func stringsToInterfaces(values []string) []interface{} {
+	var result []interface{}
+	for _, value := range values {
+		result = append(result, value)
+	}
+	return result
+}
+
+// validateSchema validates a configuration value against a schema and adds the problems found to problems
+func validateSchema(schema *jsonSchema, value interface{}, path string, problems *[]schemaProblem) {
+	if types := schemaTypes(schema); len(types) > 0 && !stringInSlice(jsonType(value), types) &&
+		!(jsonType(value) == "integer" && stringInSlice("number", types)) {
+		*problems = append(*problems, schemaProblem{path, fmt.Sprintf("must be %s, not %s", describeTypes(types), describeType(jsonType(value)))})
+		return
+	}
+	if schema.Enum != nil && !valueInSlice(value, schema.Enum) {
+		*problems = append(*problems, schemaProblem{path, fmt.Sprintf("must be one of %s", describeEnum(schema.Enum))})
+		return
+	}
+	if schema.Const != nil && !reflect.DeepEqual(schema.Const, value) {
+		*problems = append(*problems, schemaProblem{path, fmt.Sprintf("must be %s", describeEnum([]interface{}{schema.Const}))})
+		return
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		validateObject(schema, v, path, problems)
+	case []interface{}:
+		if schema.Items != nil {
+			for i, item := range v {
+				validateSchema(schema.Items, item, fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	}
+
+	for _, sub := range schema.AllOf {
+		if sub.If == nil {
+			validateSchema(sub, value, path, problems)
+		} else if matchesSchema(sub.If, value) && sub.Then != nil {
+			validateSchema(sub.Then, value, path, problems)
+		}
+	}
+}
+
+func validateObject(schema *jsonSchema, object map[string]interface{}, path string, problems *[]schemaProblem) {
+	for _, required := range schema.Required {
+		if !hasSchemaKey(schema, object, required) {
+			*problems = append(*problems, schemaProblem{path, fmt.Sprintf("missing required property '%s'", required)})
+		}
+	}
+
+	keys := make([]string, 0, len(object))
+	for key := range object {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		keyPath := configPath(path, key)
+		if property := schemaProperty(schema, key); property != nil {
+			validateSchema(property, object[key], keyPath, problems)
+			continue
+		}
+		switch additional := schema.AdditionalProperties.(type) {
+		case *jsonSchema:
+			validateSchema(additional, object[key], keyPath, problems)
+		case bool:
+			if additional {
+				continue
+			}
+			message := fmt.Sprintf("unknown property '%s'", key)
+			if suggestion := closestProperty(key, schema.Properties); suggestion != "" {
+				message = fmt.Sprintf("%s, did you mean '%s'?", message, suggestion)
+			}
+			*problems = append(*problems, schemaProblem{keyPath, message})
+		}
+	}
+}
+
+func schemaProperty(schema *jsonSchema, key string) *jsonSchema {
+	if property, ok := schema.Properties[key]; ok {
+		return property
+	} else if !schema.caseInsensitive {
+		return nil
+	}
+	for name, property := range schema.Properties {
+		if strings.EqualFold(name, key) {
+			return property
+		}
+	}
+	return nil
+}
+
+// hasSchemaKey reports whether an object has the property name, regardless of case if the schema is case-insensitive
+func hasSchemaKey(schema *jsonSchema, object map[string]interface{}, name string) bool {
+	if _, ok := object[name]; ok {
+		return true
+	} else if !schema.caseInsensitive {
+		return false
+	}
+	for key := range object {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesSchema(schema *jsonSchema, value interface{}) bool {
+	var problems []schemaProblem
+	validateSchema(schema, value, "", &problems)
+	return len(problems) == 0
+}
+
+func schemaTypes(schema *jsonSchema) []string {
+	switch types := schema.Type.(type) {
+	case string:
+		return []string{types}
+	case []string:
+		return types
+	}
+	return nil
+}
+
+// jsonType returns the JSON Schema type of a decoded JSON value
+func jsonType(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	}
+	return "unknown"
+}
+
+func describeTypes(types []string) string {
+	var names []string
+	for _, t := range types {
+		if t != "null" {
+			names = append(names, describeType(t))
+		}
+	}
+	return strings.Join(names, " or ")
+}
+
+func describeType(t string) string {
+	switch t {
+	case "null":
+		return "null"
+	case "array":
+		return "a list"
+	case "integer", "object":
+		return fmt.Sprintf("an %s", t)
+	}
+	return fmt.Sprintf("a %s", t)
+}
+
+func describeEnum(values []interface{}) string {
+	var names []string
+	for _, value := range values {
+		if value == nil {
+			continue
+		}
+		data, _ := json.Marshal(value)
+		names = append(names, string(data))
+	}
+	return strings.Join(names, ", ")
+}
+
+func valueInSlice(value interface{}, values []interface{}) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// closestProperty returns the property most similar to a misspelled key, if any is similar enough
+func closestProperty(key string, properties map[string]*jsonSchema) string {
+	closest, closestDistance := "", 3
+	for name := range properties {
+		if distance := editDistance(strings.ToLower(key), strings.ToLower(name)); distance < closestDistance || (distance == closestDistance && name < closest) {
+			closest, closestDistance = name, distance
+		}
+	}
+	if closestDistance > 2 {
+		return ""
+	}
+	return closest
+}
+
+// editDistance returns the Levenshtein distance of two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// validateConfigData validates the content of a configuration file against the schema of the configuration
+func validateConfigData(data map[string]interface{}) []schemaProblem {
+	var problems []schemaProblem
+	validateSchema(configSchema(), data, "", &problems)
+	return problems
+}
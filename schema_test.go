@@ -0,0 +1,51 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestValidateConfigData(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   string
+		problems []string
+	}{
+		{"valid", `{"srcDir": "./src", "tasks": {"A": {"globs": [".js"], "actions": [{"action": "concat", "options": {"output": "/a.js"}}]}}}`, nil},
+		{"schema and extends", `{"$schema": "web-build.schema.json", "extends": "base.json", "include": ["a.json"]}`, nil},
+		{"null values", `{"srcDir": null, "tasks": {"A": null}}`, nil},
+		{"case-folded keys", `{"SrcDir": "./src", "Tasks": {"A": {"Globs": [".js"], "Actions": [{"Action": "concat", "Options": {"output": "/a.js"}}]}}}`, nil},
+		{"unknown key with suggestion", `{"tasks": {"A": {"globes": [".js"]}}}`,
+			[]string{"tasks.A.globes: unknown property 'globes', did you mean 'globs'?"}},
+		{"unknown key without suggestion", `{"colors": true}`,
+			[]string{"colors: unknown property 'colors'"}},
+		{"option keys are case-sensitive", `{"tasks": {"A": {"actions": [{"action": "concat", "options": {"Output": "/a.js"}}]}}}`,
+			[]string{"tasks.A.actions[0].options: missing required property 'output'", "tasks.A.actions[0].options.Output: unknown property 'Output', did you mean 'output'?"}},
+		{"missing required options", `{"tasks": {"A": {"actions": [{"action": "concat"}]}}}`,
+			[]string{"tasks.A.actions[0]: missing required property 'options'"}},
+		{"missing required option", `{"tasks": {"A": {"actions": [{"action": "concat", "options": {"separator": ";"}}]}}}`,
+			[]string{"tasks.A.actions[0].options: missing required property 'output'"}},
+		{"missing action", `{"tasks": {"A": {"actions": [{"options": {}}]}}}`,
+			[]string{"tasks.A.actions[0]: missing required property 'action'"}},
+		{"invalid action", `{"tasks": {"A": {"actions": [{"action": "minify"}]}}}`,
+			[]string{`tasks.A.actions[0].action: must be one of "collate", "concat", "js-minify", "sass", "shell", "png-sprite", "favicons", "bundle", "css-bundle", "css-prefix", "css-purge", "markdown", "json-merge", "i18n", "sitemap", "precache-manifest", "sri", "html-inject", "critical-css", "inline"`}},
+		{"wrong types", `{"srcDir": 1, "tasks": {"A": {"globs": ".js"}}}`,
+			[]string{"srcDir: must be a string, not an integer", "tasks.A.globs: must be a list, not a string"}},
+		{"profile options allow null", `{"profiles": {"prod": {"options": {"concat": {"output": null}}}}}`, nil},
+	}
+	for _, test := range tests {
+		var data map[string]interface{}
+		if err := json.Unmarshal([]byte(test.config), &data); err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		var problems []string
+		for _, problem := range validateConfigData(data) {
+			problems = append(problems, fmt.Sprintf("%s: %s", problem.path, problem.message))
+		}
+		if !reflect.DeepEqual(problems, test.problems) {
+			t.Errorf("%s: validateConfigData() = %q, want %q", test.name, problems, test.problems)
+		}
+	}
+}
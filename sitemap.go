@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type sitemapAction struct{}
+
+// sitemapLimit is the maximum number of URLs allowed in a single sitemap
+const sitemapLimit = 50000
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+type sitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	Xmlns    string       `xml:"xmlns,attr"`
+	Sitemaps []sitemapURL `xml:"sitemap"`
+}
+
+func (action sitemapAction) Action(files []string, options map[string]interface{}) (outputFiles []string) {
+	baseURL := strings.TrimSuffix(actionSetting(options, "baseUrl", ""), "/")
+	if baseURL == "" {
+		errorMsg("No 'baseUrl' option or target setting defined for 'sitemap' action. Skipping task...", nil)
+		return files
+	}
+
+	buildFiles, _, err := filesInPath(config.BuildDir)
+	if err != nil {
+		errorMsg(fmt.Sprintf("Folder '%s' not found.", config.BuildDir), err)
+		return files
+	}
+
+	include := optionStrings(options, "include")
+	if len(include) == 0 {
+		include = []string{".html"}
+	}
+	for _, exclude := range optionStrings(options, "exclude") {
+		include = append(include, fmt.Sprintf("!%s", strings.TrimPrefix(exclude, "!")))
+	}
+
+	pages := globFiles(include, config.BuildDir, buildFiles)
+	sort.Strings(pages)
+
+	var urls []sitemapURL
+	for _, page := range pages {
+		relative := strings.TrimPrefix(page, config.BuildDir)
+		location := relative
+		if path.Base(location) == "index.html" {
+			location = strings.TrimSuffix(location, "index.html")
+		}
+		urls = append(urls, sitemapURL{Loc: fmt.Sprintf("%s%s", baseURL, location), LastMod: pageLastModified(page, relative)})
+	}
+
+	outputFile := fmt.Sprintf("%s%s", config.BuildDir, optionString(options, "output", "/sitemap.xml"))
+	if len(urls) <= sitemapLimit {
+		if err = writeXMLFile(outputFile, sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9", URLs: urls}); err != nil {
+			errorMsg(fmt.Sprintf("Could not write to '%s' in 'sitemap' action.", outputFile), err)
+			return files
+		}
+		outputFiles = append(outputFiles, outputFile)
+	} else {
+		// Split the sitemap into shards referenced by a sitemap index
+		index := sitemapIndex{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+		ext := filepath.Ext(outputFile)
+		for i := 0; i*sitemapLimit < len(urls); i++ {
+			shard := fmt.Sprintf("%s-%d%s", outputFile[:len(outputFile)-len(ext)], i+1, ext)
+			end := minInt((i+1)*sitemapLimit, len(urls))
+			if err = writeXMLFile(shard, sitemapURLSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9", URLs: urls[i*sitemapLimit : end]}); err != nil {
+				errorMsg(fmt.Sprintf("Could not write to '%s' in 'sitemap' action.", shard), err)
+				return files
+			}
+			outputFiles = append(outputFiles, shard)
+			index.Sitemaps = append(index.Sitemaps, sitemapURL{Loc: fmt.Sprintf("%s%s", baseURL, strings.TrimPrefix(shard, config.BuildDir)), LastMod: newestLastMod(urls[i*sitemapLimit : end])})
+		}
+		if err = writeXMLFile(outputFile, index); err != nil {
+			errorMsg(fmt.Sprintf("Could not write to '%s' in 'sitemap' action.", outputFile), err)
+			return files
+		}
+		outputFiles = append(outputFiles, outputFile)
+	}
+
+	if optionBool(options, "robots", true) {
+		robotsFile := fmt.Sprintf("%s/robots.txt", config.BuildDir)
+		var robots bytes.Buffer
+		robots.WriteString("User-agent: *\n")
+		disallow := optionStrings(options, "disallow")
+		if len(disallow) == 0 {
+			robots.WriteString("Disallow:\n")
+		}
+		for _, rule := range disallow {
+			fmt.Fprintf(&robots, "Disallow: %s\n", rule)
+		}
+		fmt.Fprintf(&robots, "\nSitemap: %s%s\n", baseURL, strings.TrimPrefix(outputFile, config.BuildDir))
+
+		if err = ioutil.WriteFile(robotsFile, robots.Bytes(), 0644); err != nil {
+			errorMsg(fmt.Sprintf("Could not write to '%s' in 'sitemap' action.", robotsFile), err)
+		} else {
+			outputFiles = append(outputFiles, robotsFile)
+		}
+	}
+
+	return outputFiles
+}
+
+// pageLastModified returns the modification date of the source file a page was built from. Pages generated from
+// Markdown are looked up by their .md source. The build file is used if no source file is found.
+func pageLastModified(page, relative string) string {
+	candidates := []string{relative, fmt.Sprintf("%s.md", strings.TrimSuffix(relative, filepath.Ext(relative)))}
+	for _, candidate := range candidates {
+		if source, _, ok := resolveTargetPath(candidate); ok {
+			page = source
+			break
+		}
+	}
+
+	info, err := os.Stat(page)
+	if err != nil {
+		return ""
+	}
+	return info.ModTime().UTC().Format("2006-01-02")
+}
+
+// newestLastMod returns the most recent modification date of a list of URLs. The dates are formatted as YYYY-MM-DD
+// so they can be compared as strings.
+func newestLastMod(urls []sitemapURL) string {
+	newest := ""
+	for _, url := range urls {
+		if url.LastMod > newest {
+			newest = url.LastMod
+		}
+	}
+	return newest
+}
+
+func writeXMLFile(file string, value interface{}) error {
+	data, err := xml.MarshalIndent(value, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, append(append([]byte(xml.Header), data...), '\n'), 0644)
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"  // Register GIF decoder for sprite images
+	_ "image/jpeg" // Register JPEG decoder for sprite images
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var spriteClassRegex = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+type pngSpriteAction struct{}
+
+type spriteImage struct {
+	file   string
+	name   string
+	image  image.Image
+	retina image.Image
+	x      int
+	y      int
+	width  int
+	height int
+}
+
+// spriteNode is a node of the binary tree used to pack images into the atlas
+type spriteNode struct {
+	x, y, width, height int
+	used                bool
+	right, down         *spriteNode
+}
+
+func (action pngSpriteAction) Action(files []string, options map[string]interface{}) (outputFiles []string) {
+	if len(files) == 0 {
+		return files
+	}
+
+	outputFile, ok := options["output"].(string)
+	if !ok {
+		errorMsg("No output file defined for 'png-sprite' action. Skipping task...", nil)
+		return files
+	}
+	outputFile = fmt.Sprintf("%s%s", config.BuildDir, outputFile)
+	ext := filepath.Ext(outputFile)
+
+	cssFile := optionString(options, "css", "")
+	if cssFile == "" {
+		cssFile = fmt.Sprintf("%s%s", outputFile[:len(outputFile)-len(ext)], ".css")
+	} else {
+		cssFile = fmt.Sprintf("%s%s", config.BuildDir, cssFile)
+	}
+
+	padding := optionInt(options, "padding", 2)
+	prefix := optionString(options, "prefix", "sprite-")
+	retina := optionBool(options, "retina", false)
+
+	images, err := loadSpriteImages(files, retina)
+	if err != nil {
+		errorMsg("Could not load images in 'png-sprite' action. Skipping task...", err)
+		return files
+	}
+	if len(images) == 0 {
+		return files
+	}
+
+	width, height := packSpriteImages(images, padding)
+
+	atlas := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for _, img := range images {
+		draw.Draw(atlas, image.Rect(img.x, img.y, img.x+img.width, img.y+img.height), img.image, img.image.Bounds().Min, draw.Src)
+	}
+	if err = writeSpritePNG(outputFile, atlas); err != nil {
+		errorMsg(fmt.Sprintf("Could not write to '%s' defined in 'png-sprite' action. Skipping task...", outputFile), err)
+		return files
+	}
+	outputFiles = append(outputFiles, outputFile)
+
+	// Retina images are placed at double the coordinates of their regular counterparts so the same positions apply
+	retinaFile := ""
+	if retina {
+		retinaAtlas := image.NewNRGBA(image.Rect(0, 0, width*2, height*2))
+		hasRetina := false
+		for _, img := range images {
+			if img.retina == nil {
+				continue
+			}
+			hasRetina = true
+			bounds := img.retina.Bounds()
+			draw.Draw(retinaAtlas, image.Rect(img.x*2, img.y*2, img.x*2+bounds.Dx(), img.y*2+bounds.Dy()), img.retina, bounds.Min, draw.Src)
+		}
+
+		if hasRetina {
+			retinaFile = fmt.Sprintf("%s%s%s", outputFile[:len(outputFile)-len(ext)], "@2x", ext)
+			if err = writeSpritePNG(retinaFile, retinaAtlas); err != nil {
+				errorMsg(fmt.Sprintf("Could not write to '%s' defined in 'png-sprite' action.", retinaFile), err)
+				retinaFile = ""
+			} else {
+				outputFiles = append(outputFiles, retinaFile)
+			}
+		}
+	}
+
+	url := optionString(options, "url", "")
+	if url == "" {
+		url, _ = filepath.Rel(filepath.Dir(cssFile), outputFile)
+		url = filepath.ToSlash(url)
+	}
+	retinaURL := ""
+	if retinaFile != "" {
+		urlExt := filepath.Ext(url)
+		retinaURL = fmt.Sprintf("%s%s%s", url[:len(url)-len(urlExt)], "@2x", urlExt)
+	}
+
+	css := spriteStylesheet(images, prefix, url, retinaURL, width, height, filepath.Ext(cssFile) == ".scss")
+	err = os.MkdirAll(filepath.Dir(cssFile), 0755)
+	if err == nil {
+		err = ioutil.WriteFile(cssFile, css, 0644)
+	}
+	if err != nil {
+		errorMsg(fmt.Sprintf("Could not write to '%s' defined in 'png-sprite' action.", cssFile), err)
+		return outputFiles
+	}
+
+	return append(outputFiles, cssFile)
+}
+
+func loadSpriteImages(files []string, retina bool) ([]*spriteImage, error) {
+	var images []*spriteImage
+	retinaImages := make(map[string]image.Image)
+
+	var relativeFiles []string
+	for _, file := range files {
+		relativeFiles = append(relativeFiles, relativePath(file))
+	}
+	base := commonPath(relativeFiles, 0)
+	if len(relativeFiles) == 1 {
+		base = fmt.Sprintf("%s/", filepath.Dir(relativeFiles[0]))
+	}
+	base = base[:strings.LastIndex(base, "/")+1]
+
+	for i, file := range files {
+		img, err := decodeImage(file)
+		if err != nil {
+			return nil, err
+		}
+
+		name := strings.TrimPrefix(relativeFiles[i], base)
+		name = name[:len(name)-len(filepath.Ext(name))]
+		if retina && strings.HasSuffix(name, "@2x") {
+			retinaImages[strings.TrimSuffix(name, "@2x")] = img
+			continue
+		}
+
+		bounds := img.Bounds()
+		images = append(images, &spriteImage{
+			file:   file,
+			name:   strings.Trim(spriteClassRegex.ReplaceAllString(name, "-"), "-"),
+			image:  img,
+			width:  bounds.Dx(),
+			height: bounds.Dy(),
+		})
+	}
+
+	// Retina images may be found before or after their regular counterparts
+	for _, img := range images {
+		name := strings.TrimPrefix(relativePath(img.file), base)
+		img.retina = retinaImages[name[:len(name)-len(filepath.Ext(name))]]
+	}
+
+	return images, nil
+}
+
+func decodeImage(file string) (image.Image, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode image '%s': %s", file, err)
+	}
+	return img, nil
+}
+
+// packSpriteImages positions all images using a growing binary tree packer and returns the atlas size
+func packSpriteImages(images []*spriteImage, padding int) (width, height int) {
+	sorted := make([]*spriteImage, len(images))
+	copy(sorted, images)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return maxInt(sorted[i].width, sorted[i].height) > maxInt(sorted[j].width, sorted[j].height)
+	})
+
+	root := &spriteNode{width: sorted[0].width + padding, height: sorted[0].height + padding}
+	for _, img := range sorted {
+		w := img.width + padding
+		h := img.height + padding
+
+		node := root.find(w, h)
+		if node == nil {
+			root = root.grow(w, h)
+			node = root.find(w, h)
+		}
+		node.split(w, h)
+		img.x = node.x
+		img.y = node.y
+	}
+
+	for _, img := range images {
+		width = maxInt(width, img.x+img.width)
+		height = maxInt(height, img.y+img.height)
+	}
+	return width, height
+}
+
+func (node *spriteNode) find(width, height int) *spriteNode {
+	if node.used {
+		if found := node.right.find(width, height); found != nil {
+			return found
+		}
+		return node.down.find(width, height)
+	} else if width <= node.width && height <= node.height {
+		return node
+	}
+	return nil
+}
+
+func (node *spriteNode) split(width, height int) {
+	node.used = true
+	node.down = &spriteNode{x: node.x, y: node.y + height, width: node.width, height: node.height - height}
+	node.right = &spriteNode{x: node.x + width, y: node.y, width: node.width - width, height: height}
+}
+
+// grow expands the tree to the right or downwards, preferring whichever keeps the atlas closest to square.
+// Images are sorted by their longest side so the new image always fits in at least one direction.
+func (node *spriteNode) grow(width, height int) *spriteNode {
+	canGrowDown := width <= node.width
+	canGrowRight := height <= node.height
+	shouldGrowRight := canGrowRight && node.height >= node.width+width
+	shouldGrowDown := canGrowDown && node.width >= node.height+height
+
+	if shouldGrowRight || (!shouldGrowDown && canGrowRight) {
+		return &spriteNode{
+			width:  node.width + width,
+			height: node.height,
+			used:   true,
+			down:   node,
+			right:  &spriteNode{x: node.width, width: width, height: node.height},
+		}
+	}
+	return &spriteNode{
+		width:  node.width,
+		height: node.height + height,
+		used:   true,
+		down:   &spriteNode{y: node.height, width: node.width, height: height},
+		right:  node,
+	}
+}
+
+func writeSpritePNG(file string, img image.Image) error {
+	err := os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err = encoder.Encode(&buffer, img); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file, buffer.Bytes(), 0644)
+}
+
+func spriteStylesheet(images []*spriteImage, prefix, url, retinaURL string, width, height int, scss bool) []byte {
+	var css bytes.Buffer
+	var selectors []string
+	for _, img := range images {
+		selectors = append(selectors, fmt.Sprintf(".%s%s", prefix, img.name))
+	}
+
+	if scss {
+		for _, img := range images {
+			fmt.Fprintf(&css, "$%s%s: %dpx %dpx %dpx %dpx;\n", prefix, img.name, -img.x, -img.y, img.width, img.height)
+		}
+		css.WriteString("\n")
+	}
+
+	fmt.Fprintf(&css, "%s {\n    background-image: url(\"%s\");\n    background-repeat: no-repeat;\n    display: inline-block;\n}\n\n", strings.Join(selectors, ",\n"), url)
+	for _, img := range images {
+		fmt.Fprintf(&css, ".%s%s {\n    background-position: %dpx %dpx;\n    width: %dpx;\n    height: %dpx;\n}\n\n", prefix, img.name, -img.x, -img.y, img.width, img.height)
+	}
+
+	if retinaURL != "" {
+		var retinaSelectors []string
+		for _, img := range images {
+			if img.retina != nil {
+				retinaSelectors = append(retinaSelectors, fmt.Sprintf("    .%s%s", prefix, img.name))
+			}
+		}
+		fmt.Fprintf(&css, "@media (-webkit-min-device-pixel-ratio: 2), (min-resolution: 192dpi) {\n%s {\n        background-image: url(\"%s\");\n        background-size: %dpx %dpx;\n    }\n}\n", strings.Join(retinaSelectors, ",\n"), retinaURL, width, height)
+	}
+
+	return css.Bytes()
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type sriAction struct{}
+
+func (action sriAction) Action(files []string, options map[string]interface{}) (outputFiles []string) {
+	// Without input files the HTML files of the build directory are used
+	if len(files) == 0 {
+		buildFiles, _, err := filesInPath(config.BuildDir)
+		if err != nil {
+			errorMsg(fmt.Sprintf("Folder '%s' not found.", config.BuildDir), err)
+			return files
+		}
+		globs := optionStrings(options, "html")
+		if len(globs) == 0 {
+			globs = []string{".html"}
+		}
+		files = globFiles(globs, config.BuildDir, buildFiles)
+	}
+
+	crossOrigin := optionString(options, "crossorigin", "anonymous")
+	digests := make(map[string]string)
+	var unresolved []string
+
+	for _, file := range files {
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not read file '%s'", file), err)
+			continue
+		}
+
+		newFile := file
+		if !strings.HasPrefix(file, config.BuildDir) {
+			newFile = fmt.Sprintf("%s%s", config.BuildDir, relativePath(file))
+		}
+
+		addIntegrity := func(tag string, reference string) string {
+			if reference == "" || isRemoteURL(reference) {
+				return tag
+			}
+			asset, _ := resolveBuildReference(newFile, reference)
+			digest, ok := digests[asset]
+			if !ok {
+				if digest, err = integrityDigest(asset); err != nil {
+					unresolved = append(unresolved, fmt.Sprintf("%s: %s", relativePath(newFile), reference))
+					return tag
+				}
+				digests[asset] = digest
+			}
+			tag = setTagAttribute(tag, "integrity", digest)
+			return setTagAttribute(tag, "crossorigin", crossOrigin)
+		}
+
+		updated := scriptTagRegex.ReplaceAllStringFunc(string(content), func(tag string) string {
+			return addIntegrity(tag, tagAttributes(tag)["src"])
+		})
+		updated = linkTagRegex.ReplaceAllStringFunc(updated, func(tag string) string {
+			attributes := tagAttributes(tag)
+			if !isStylesheetLink(attributes) {
+				return tag
+			}
+			return addIntegrity(tag, attributes["href"])
+		})
+
+		err = os.MkdirAll(filepath.Dir(newFile), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(newFile, []byte(updated), 0644)
+		}
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not write to '%s' in 'sri' action.", newFile), err)
+			continue
+		}
+		outputFiles = append(outputFiles, newFile)
+	}
+
+	if len(unresolved) > 0 {
+		sort.Strings(unresolved)
+		errorMsg(fmt.Sprintf("Could not resolve the following references in 'sri' action:\n  %s", strings.Join(unresolved, "\n  ")), nil)
+	}
+
+	return outputFiles
+}
+
+// integrityDigest returns the sha384 subresource integrity value of a file
+func integrityDigest(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	digest := sha512.Sum384(data)
+	return fmt.Sprintf("sha384-%s", base64.StdEncoding.EncodeToString(digest[:])), nil
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var mermaidIDRegex = regexp.MustCompile(`[^A-Za-z0-9]`)
+
+// dotIDReplacer escapes the ':' separating the parts of DOT IDs in names
+var dotIDReplacer = strings.NewReplacer("%", "%25", ":", "%3A")
+
+func targetsCommand(args []string) {
+	flags := flag.NewFlagSet("targets", flag.ExitOnError)
+	format := flags.String("format", "tree", "The output format: 'tree' to show and validate the targets, 'dot' or 'mermaid' to export the targets and tasks as a graph.")
+	flags.Usage = func() {
+		fmt.Printf("Usage of targets:\n  web-build targets [FLAGS]\n\n  Flags:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if !loadConfig() {
+		os.Exit(1)
+	}
+
+	switch *format {
+	case "tree":
+		printTargetTree()
+	case "dot":
+		fmt.Print(targetGraphDOT())
+	case "mermaid":
+		fmt.Print(targetGraphMermaid())
+	default:
+		errorMsg(fmt.Sprintf("Invalid format '%s'. Use 'tree', 'dot' or 'mermaid'.", *format), nil)
+		os.Exit(1)
+	}
+}
+
+// targetProblems validates the targets. Errors are dangling dependencies and circular dependencies, warnings are
+// targets without a source directory and source directories without a target.
+func targetProblems() (errors []string, warnings []string) {
+	names := sortedTargetNames()
+	cycles := make(map[string]bool)
+	for _, name := range names {
+		dependency := config.Targets[name].Dependency
+		if dependency != "" {
+			if _, ok := config.Targets[dependency]; !ok {
+				errors = append(errors, fmt.Sprintf("Target '%s' depends on undefined target '%s'.", name, dependency))
+			}
+		}
+
+		// Follow the dependencies until they end or return to a target already visited
+		chain := []string{name}
+		for target := dependency; target != ""; target = config.Targets[target].Dependency {
+			if index := indexInSlice(target, chain); index > -1 {
+				cycle := chain[index:]
+				if !cycles[strings.Join(rotateToFirst(cycle), ",")] {
+					cycles[strings.Join(rotateToFirst(cycle), ",")] = true
+					errors = append(errors, fmt.Sprintf("Circular target dependency '%s'.", strings.Join(append(cycle, target), "' -> '")))
+				}
+				break
+			}
+			chain = append(chain, target)
+		}
+	}
+
+	if len(config.Targets) == 0 {
+		return errors, warnings
+	}
+	for _, name := range names {
+		if info, err := os.Stat(fmt.Sprintf("%s/%s", config.SrcDir, name)); err != nil || !info.IsDir() {
+			warnings = append(warnings, fmt.Sprintf("Target '%s' has no directory in '%s'.", name, config.SrcDir))
+		}
+	}
+	dirs, _ := ioutil.ReadDir(config.SrcDir)
+	for _, dir := range dirs {
+		if _, ok := config.Targets[dir.Name()]; dir.IsDir() && !ok && !strings.HasPrefix(dir.Name(), ".") {
+			warnings = append(warnings, fmt.Sprintf("Directory '%s/%s' does not belong to any target.", config.SrcDir, dir.Name()))
+		}
+	}
+	return errors, warnings
+}
+
+func indexInSlice(value string, slice []string) int {
+	for i, item := range slice {
+		if item == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// rotateToFirst rotates a cycle so it starts with its alphabetically first element, so each cycle is only
+// reported once
+func rotateToFirst(cycle []string) []string {
+	first := 0
+	for i, item := range cycle {
+		if item < cycle[first] {
+			first = i
+		}
+	}
+	return append(append([]string{}, cycle[first:]...), cycle[:first]...)
+}
+
+func sortedTargetNames() []string {
+	var names []string
+	for name := range config.Targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func printTargetTree() {
+	if len(config.Targets) == 0 {
+		fmt.Printf("No targets are defined in %s.\n", configFile)
+		return
+	}
+
+	children := make(map[string][]string)
+	var roots []string
+	for _, name := range sortedTargetNames() {
+		dependency := config.Targets[name].Dependency
+		if _, ok := config.Targets[dependency]; dependency == "" || !ok {
+			roots = append(roots, name)
+		} else {
+			children[dependency] = append(children[dependency], name)
+		}
+	}
+
+	fmt.Printf("Targets:\n")
+	printed := make(map[string]bool)
+	var printTarget func(name, indent string, last bool)
+	printTarget = func(name, indent string, last bool) {
+		printed[name] = true
+		branch, childIndent := "├─ ", "│  "
+		if last {
+			branch, childIndent = "└─ ", "   "
+		}
+		label := name
+		if name == config.Target {
+			label = fmt.Sprintf("%s %s", fmtGreen(name), fmtGreen("(current)"))
+		}
+		if dependency := config.Targets[name].Dependency; dependency != "" {
+			if _, ok := config.Targets[dependency]; !ok {
+				label = fmt.Sprintf("%s (depends on undefined target '%s')", label, dependency)
+			}
+		}
+		fmt.Printf("  %s%s%s\n", indent, branch, label)
+		for i, child := range children[name] {
+			printTarget(child, fmt.Sprintf("%s%s", indent, childIndent), i == len(children[name])-1)
+		}
+	}
+	for i, root := range roots {
+		printTarget(root, "", i == len(roots)-1)
+	}
+
+	// Targets in circular dependencies cannot be reached from a root
+	var unreachable []string
+	for _, name := range sortedTargetNames() {
+		if !printed[name] {
+			unreachable = append(unreachable, name)
+		}
+	}
+	if len(unreachable) > 0 {
+		fmt.Printf("  Not reachable from any root: %s\n", strings.Join(unreachable, ", "))
+	}
+
+	errors, warnings := targetProblems()
+	if len(errors) == 0 && len(warnings) == 0 {
+		fmt.Printf("\nNo problems found.\n")
+		return
+	}
+	fmt.Printf("\nProblems:\n")
+	for _, problem := range errors {
+		fmt.Printf("  %s %s\n", fmtRed("error:"), problem)
+	}
+	for _, problem := range warnings {
+		fmt.Printf("  warning: %s\n", problem)
+	}
+}
+
+func sortedTaskNames() []string {
+	var names []string
+	for name := range config.Tasks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedTaskDependencies(name string) []string {
+	dependencies := taskDependencies(name, config.Tasks)
+	sort.Strings(dependencies)
+	return dependencies
+}
+
+// targetGraphDOT returns the targets and the tasks with their actions as a Graphviz DOT graph
+func targetGraphDOT() string {
+	var out bytes.Buffer
+	out.WriteString("digraph \"web-build\" {\n    rankdir=LR;\n    node [fontname=\"sans-serif\"];\n\n")
+
+	targetID := func(name string) string {
+		return fmt.Sprintf("target:%s", dotIDReplacer.Replace(name))
+	}
+	taskID := func(name string) string {
+		return fmt.Sprintf("task:%s", dotIDReplacer.Replace(name))
+	}
+
+	out.WriteString("    subgraph cluster_targets {\n        label=\"Targets\";\n")
+	for _, name := range sortedTargetNames() {
+		fmt.Fprintf(&out, "        %q [label=%q];\n", targetID(name), name)
+	}
+	for _, name := range sortedTargetNames() {
+		if dependency := config.Targets[name].Dependency; dependency != "" {
+			fmt.Fprintf(&out, "        %q -> %q;\n", targetID(name), targetID(dependency))
+		}
+	}
+	out.WriteString("    }\n\n")
+
+	out.WriteString("    subgraph cluster_tasks {\n        label=\"Tasks\";\n")
+	for _, name := range sortedTaskNames() {
+		task := config.Tasks[name]
+		id := taskID(name)
+		fmt.Fprintf(&out, "        %q [label=%q, shape=box];\n", id, name)
+		previous := id
+		for i, action := range task.Actions {
+			actionID := fmt.Sprintf("%s:%d", id, i)
+			fmt.Fprintf(&out, "        %q [label=%q, shape=ellipse];\n", actionID, action.Action)
+			fmt.Fprintf(&out, "        %q -> %q;\n", previous, actionID)
+			previous = actionID
+		}
+	}
+	for _, name := range sortedTaskNames() {
+		for _, after := range sortedTaskDependencies(name) {
+			fmt.Fprintf(&out, "        %q -> %q [style=dashed, label=\"after\"];\n", taskID(after), taskID(name))
+		}
+	}
+	out.WriteString("    }\n")
+
+	for _, name := range sortedTaskNames() {
+		for _, target := range config.Tasks[name].Targets {
+			fmt.Fprintf(&out, "    %q -> %q [style=dotted];\n", taskID(name), targetID(target))
+		}
+	}
+	out.WriteString("}\n")
+	return out.String()
+}
+
+// targetGraphMermaid returns the targets and the tasks with their actions as a Mermaid flowchart
+func targetGraphMermaid() string {
+	var out bytes.Buffer
+	out.WriteString("flowchart LR\n")
+
+	targetID := func(name string) string {
+		return fmt.Sprintf("target_%s", mermaidID(name))
+	}
+	taskID := func(name string) string {
+		return fmt.Sprintf("task_%s", mermaidID(name))
+	}
+
+	out.WriteString("    subgraph Targets\n")
+	for _, name := range sortedTargetNames() {
+		fmt.Fprintf(&out, "        %s[%s]\n", targetID(name), mermaidLabel(name))
+	}
+	for _, name := range sortedTargetNames() {
+		if dependency := config.Targets[name].Dependency; dependency != "" {
+			fmt.Fprintf(&out, "        %s --> %s\n", targetID(name), targetID(dependency))
+		}
+	}
+	out.WriteString("    end\n")
+
+	out.WriteString("    subgraph Tasks\n")
+	for _, name := range sortedTaskNames() {
+		fmt.Fprintf(&out, "        %s[%s]\n", taskID(name), mermaidLabel(name))
+		previous := taskID(name)
+		for i, action := range config.Tasks[name].Actions {
+			actionID := fmt.Sprintf("%s__%d", taskID(name), i)
+			fmt.Fprintf(&out, "        %s --> %s([%s])\n", previous, actionID, mermaidLabel(action.Action))
+			previous = actionID
+		}
+	}
+	for _, name := range sortedTaskNames() {
+		for _, after := range sortedTaskDependencies(name) {
+			fmt.Fprintf(&out, "        %s -. after .-> %s\n", taskID(after), taskID(name))
+		}
+	}
+	out.WriteString("    end\n")
+
+	for _, name := range sortedTaskNames() {
+		for _, target := range config.Tasks[name].Targets {
+			fmt.Fprintf(&out, "    %s -.- %s\n", taskID(name), targetID(target))
+		}
+	}
+	return out.String()
+}
+
+// mermaidID escapes the characters of a name that are not allowed in Mermaid IDs as '_' followed by their hex code,
+// including '_' itself so different names never have the same ID. Escaped IDs never contain '__', which separates
+// the index of an action from the ID of its task.
+func mermaidID(name string) string {
+	return mermaidIDRegex.ReplaceAllStringFunc(name, func(s string) string {
+		return fmt.Sprintf("_%x", s)
+	})
+}
+
+func mermaidLabel(label string) string {
+	return fmt.Sprintf("\"%s\"", strings.Replace(label, "\"", "#quot;", -1))
+}
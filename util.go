@@ -23,6 +23,13 @@ func fmtGreen(a ...interface{}) string {
 	return fmt.Sprint("\x1b[32m", fmt.Sprint(a...), "\x1b[39m")
 }
 
+func fmtRed(a ...interface{}) string {
+	if runtime.GOOS == "windows" {
+		return fmt.Sprint(a...)
+	}
+	return fmt.Sprint("\x1b[31m", fmt.Sprint(a...), "\x1b[39m")
+}
+
 func debug(val interface{}) {
 	fmt.Printf("%+v\n", val)
 }
@@ -96,3 +103,40 @@ func stringInSlice(value string, slice []string) bool {
 	}
 	return false
 }
+
+func optionString(options map[string]interface{}, key string, fallback string) string {
+	if value, ok := options[key].(string); ok {
+		return value
+	}
+	return fallback
+}
+
+func optionInt(options map[string]interface{}, key string, fallback int) int {
+	if value, ok := options[key].(float64); ok {
+		return int(value)
+	}
+	return fallback
+}
+
+func optionBool(options map[string]interface{}, key string, fallback bool) bool {
+	if value, ok := options[key].(bool); ok {
+		return value
+	}
+	return fallback
+}
+
+func optionStrings(options map[string]interface{}, key string) []string {
+	switch value := options[key].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		var values []string
+		for _, item := range value {
+			if str, ok := item.(string); ok {
+				values = append(values, str)
+			}
+		}
+		return values
+	}
+	return nil
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"archive/zip"
 	"flag"
 	"fmt"
 	"io/ioutil"
@@ -14,25 +13,45 @@ import (
 	"github.com/fsnotify/fsnotify"
 )
 
-const version string = "1.3.3"
+const version string = "1.4.0"
 
-var argZip string
+var argConfig string
+var argArchive string
+var argArchivePrefix string
+var argManifest string
+var argPrintConfig bool
+var argProfile string
 var argTarget string
 var argVersion bool
 var argWatch bool
 var config Config
 var srcFiles []string
 var srcDirs []string
+var taskOutputs map[string][]string
+var taskOutputsMutex sync.Mutex
 
 func main() {
 	initFlags()
+	if argPrintConfig {
+		printConfig()
+		return
+	}
 
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
+	// Commands follow the flags
+	if args := flag.Args(); len(args) > 0 {
+		switch args[0] {
 		case "init":
-			initializeEmptyProject()
+			initializeEmptyProject(args[1:])
 		case "clean":
 			loadConfigAndClean()
+		case "diff":
+			diffCommand(args[1:])
+		case "explain":
+			explainCommand(args[1:])
+		case "targets":
+			targetsCommand(args[1:])
+		case "schema":
+			schemaCommand(args[1:])
 		default:
 			run(nil, true)
 		}
@@ -43,13 +62,19 @@ func main() {
 
 func initFlags() {
 	flag.Usage = func() {
-		fmt.Printf("Usage of %s:\n  web-build [COMMAND] OR web-build [FLAGS]\n\n", os.Args[0])
-		fmt.Printf("  Commands:\n  init\n\tInitialize an empty project complete with: source directory, 'common' target and a default 'web-build.json.'\n  clean\n\tClear the build directory\n\n")
+		fmt.Printf("Usage of %s:\n  web-build [FLAGS] [COMMAND]\n\n", os.Args[0])
+		fmt.Printf("  Commands:\n  init\n\tInitialize an empty project complete with: source directory, 'common' target and a default 'web-build.json.' Run 'web-build init -h' for details.\n  clean\n\tClear the build directory\n  diff\n\tCompare two builds, archives or build manifests, or the source files of two targets. Run 'web-build diff -h' for details.\n  explain\n\tShow which target supplies a file and which tasks and actions build it. Run 'web-build explain -h' for details.\n  targets\n\tShow and validate the target tree or export it with the tasks as a Graphviz DOT or Mermaid graph. Run 'web-build targets -h' for details.\n  schema\n\tPrint the JSON Schema of the configuration. Run 'web-build schema -h' for details.\n\n")
 		fmt.Printf("  Flags:\n")
 		flag.PrintDefaults()
 	}
-	flag.StringVar(&argTarget, "target", "", "Specify the target to build. This will override the target specified in 'web-build.json'.")
-	flag.StringVar(&argZip, "zip", "", "Compress the build upon completion of program. Specify the location and name of the zip file. Example: './app.zip'")
+	flag.StringVar(&argConfig, "config", "", "Specify the configuration file. By default 'web-build.json', 'web-build.jsonc', 'web-build.json5', 'web-build.yaml', 'web-build.yml' or 'web-build.toml' is searched for in the current directory and its parent directories.")
+	flag.StringVar(&argTarget, "target", "", "Specify the target to build. This will override the target specified in 'web-build.json'. Separate multiple targets with commas to build them one after another.")
+	flag.StringVar(&argArchive, "archive", "", "Archive the build upon completion of program. Specify the location and name of the archive, the format is determined by its extension (.zip, .tar.gz or .tar.zst). '{target}' and '{version}' are replaced with the target and the version in 'web-build.json'. Example: './dist/{target}-{version}.zip'")
+	flag.StringVar(&argArchive, "zip", "", "Alias of -archive.")
+	flag.StringVar(&argManifest, "manifest", "", "Write a manifest of the build with the hashes, actions and source files of every file upon completion of program. '{target}' and '{version}' are replaced like in -archive. Example: './build/build-manifest.json'")
+	flag.StringVar(&argArchivePrefix, "archive-prefix", "", "Place the files in the archive in a directory. '{target}' and '{version}' are replaced like in -archive.")
+	flag.StringVar(&argProfile, "profile", "", "Specify the profile to build with, i.e. 'dev' or 'prod'. This will override the profile specified in 'web-build.json'.")
+	flag.BoolVar(&argPrintConfig, "print-config", false, "Print the configuration merged with the files it extends and includes, with its variables replaced for the target given with -target, then exit.")
 	flag.BoolVar(&argVersion, "version", false, "Show the current version")
 	flag.BoolVar(&argWatch, "watch", false, "Runs web-build and watches all files specified by user configuration globs for changes.")
 	flag.Parse()
@@ -62,7 +87,7 @@ func initFlags() {
 
 func loadConfigAndClean() {
 	var err error
-	config, err = parseConfig()
+	config, err = parseConfig("")
 	if err != nil {
 		errorMsg("Could not parse configuration file.", err)
 		return
@@ -70,6 +95,24 @@ func loadConfigAndClean() {
 	clean()
 }
 
+// loadConfig parses the configuration and lists the source files for commands that do not build
+func loadConfig() bool {
+	var err error
+	config, err = parseConfig("")
+	if err != nil {
+		errorMsg("Could not parse configuration file.", err)
+		return false
+	}
+
+	resolveConfigPaths()
+	srcFiles, srcDirs, err = filesInPath(config.SrcDir)
+	if err != nil {
+		errorMsg(fmt.Sprintf("Folder '%s' not found.\n", config.SrcDir), err)
+		return false
+	}
+	return true
+}
+
 func clean() {
 	var err error
 	err = os.RemoveAll(config.BuildDir)
@@ -79,7 +122,15 @@ func clean() {
 	}
 }
 
-func initializeEmptyProject() {
+func initializeEmptyProject(args []string) {
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	preset := flags.String("preset", "", "A configuration file to extend instead of the default configuration, i.e. a shared preset. The source directories of its targets are created.")
+	flags.Usage = func() {
+		fmt.Printf("Usage of init:\n  web-build init [FLAGS]\n\n  Flags:\n")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
 	files, err := ioutil.ReadDir("./")
 	if err != nil {
 		errorMsg("Cannot access current working directory to initialize web-build", err)
@@ -89,10 +140,20 @@ func initializeEmptyProject() {
 		os.Exit(1)
 	}
 
-	data, err := configTemplate()
-	if err != nil {
-		errorMsg("Could not initialize due to template decoding error. Exiting.", err)
-		os.Exit(1)
+	var data []byte
+	dirs := []string{"./src/common"}
+	if *preset != "" {
+		data, dirs, err = presetTemplate(*preset)
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not read preset '%s'. Exiting.", *preset), err)
+			os.Exit(1)
+		}
+	} else {
+		data, err = configTemplate()
+		if err != nil {
+			errorMsg("Could not initialize due to template decoding error. Exiting.", err)
+			os.Exit(1)
+		}
 	}
 
 	err = ioutil.WriteFile("web-build.json", data, 0744)
@@ -101,58 +162,95 @@ func initializeEmptyProject() {
 		os.Exit(1)
 	}
 
-	err = os.MkdirAll("./src/common", 0744)
-	if err != nil {
-		errorMsg("Could not create source directory. Exiting.", err)
-		os.Exit(1)
+	for _, dir := range dirs {
+		err = os.MkdirAll(dir, 0744)
+		if err != nil {
+			errorMsg("Could not create source directory. Exiting.", err)
+			os.Exit(1)
+		}
 	}
 }
 
 func run(done chan<- bool, runWatcher bool) {
-	var err error
-	start := timestamp()
-
 	if done != nil {
 		defer func() { done <- true }()
 	}
 
-	config, err = parseConfig()
+	targets := strings.Split(argTarget, ",")
+	if len(targets) > 1 && argWatch {
+		errorMsg("Cannot watch multiple targets.", nil)
+		return
+	} else if len(targets) > 1 && argArchive != "" && !strings.Contains(argArchive, "{target}") {
+		errorMsg("The archive name must contain '{target}' when building multiple targets.", nil)
+		return
+	}
+
+	for _, target := range targets {
+		if !build(strings.TrimSpace(target)) {
+			return
+		}
+	}
+
+	if runWatcher && argWatch {
+		watch()
+	}
+}
+
+// build builds a target, or the target in the configuration if target is empty. It reports whether the build
+// could be started.
+func build(target string) bool {
+	var err error
+	start := timestamp()
+
+	config, err = parseConfig(target)
 	if err != nil {
 		errorMsg("Could not parse configuration file.", err)
-		return
+		return false
 	}
 
-	err = setup()
+	err = setup(target)
 	if err != nil {
 		errorMsg("Error during setup.", err)
-		return
+		return false
 	}
 
 	if len(config.Targets) > 0 {
 		fmt.Printf("Building target: %s\n", fmtCyan(config.Target))
 	}
+	if config.Profile != "" {
+		fmt.Printf("Profile: %s\n", fmtCyan(config.Profile))
+	}
 	fmt.Printf("Running Tasks...\n")
+	taskOutputs = make(map[string][]string)
+	buildRecords = make(map[string]*buildRecord)
 	runTasks(config.Tasks)
 	fmt.Printf("Completed in: %s\n\n", fmtCyan(timestamp()-start, "ms"))
 
-	if argZip != "" {
-		createZip(argZip)
+	// The manifest is written first so it is included in the archive if it is written to the build directory
+	if argManifest != "" {
+		writeBuildManifest(expandPlaceholders(argManifest))
 	}
-
-	if runWatcher && argWatch {
-		watch()
+	if argArchive != "" {
+		createArchive(expandPlaceholders(argArchive), expandPlaceholders(argArchivePrefix))
 	}
+	return true
 }
 
-func setup() error {
-	if argTarget != "" {
-		config.Target = argTarget
-		if !checkValidTarget(argTarget, config) {
-			errorMsg(fmt.Sprintf("The target '%s' is invalid.", argTarget), nil)
-			return &invalidTargetError{argTarget}
+func setup(target string) error {
+	if target != "" {
+		config.Target = target
+		if !checkValidTarget(target, config) {
+			errorMsg(fmt.Sprintf("The target '%s' is invalid.", target), nil)
+			return &invalidTargetError{target}
 		}
 	}
 
+	if errs, _ := targetProblems(); len(errs) > 0 {
+		errorMsg(fmt.Sprintf("Invalid targets in %s:\n  %s", configFile, strings.Join(errs, "\n  ")), nil)
+		return fmt.Errorf("invalid targets")
+	}
+
+	resolveConfigPaths()
 	if _, err := os.Stat(config.SrcDir); err != nil {
 		errorMsg(fmt.Sprintf("Source directory '%s' does not exist.", config.SrcDir), nil)
 		return err
@@ -161,12 +259,6 @@ func setup() error {
 		return err
 	}
 
-	// Prevent relative paths in config (i.e. use of ../../) from messing things up for SrcDir and BuildDir
-	config.SrcDir, _ = filepath.Abs(config.SrcDir)
-	config.SrcDir = filepath.ToSlash(config.SrcDir)
-	config.BuildDir, _ = filepath.Abs(config.BuildDir)
-	config.BuildDir = filepath.ToSlash(config.BuildDir)
-
 	var wg sync.WaitGroup
 	wg.Add(2)
 	go func() {
@@ -185,8 +277,30 @@ func setup() error {
 	return nil
 }
 
+// resolveConfigPaths makes SrcDir and BuildDir absolute so that relative paths in config (i.e. use of ../../)
+// do not mess things up. Relative paths are relative to the directory of the configuration file.
+func resolveConfigPaths() {
+	if !filepath.IsAbs(config.SrcDir) {
+		config.SrcDir = filepath.Join(configDir(), config.SrcDir)
+	}
+	if !filepath.IsAbs(config.BuildDir) {
+		config.BuildDir = filepath.Join(configDir(), config.BuildDir)
+	}
+	config.SrcDir, _ = filepath.Abs(config.SrcDir)
+	config.SrcDir = filepath.ToSlash(config.SrcDir)
+	config.BuildDir, _ = filepath.Abs(config.BuildDir)
+	config.BuildDir = filepath.ToSlash(config.BuildDir)
+}
+
 func runTasks(tasks map[string]Task) {
 	var wg sync.WaitGroup
+	finished := make(map[string]chan bool)
+	for name, task := range tasks {
+		if shouldRunForTarget(config.Target, task.Targets) {
+			finished[name] = make(chan bool)
+		}
+	}
+
 	for name, task := range tasks {
 		if !shouldRunForTarget(config.Target, task.Targets) {
 			continue
@@ -196,6 +310,14 @@ func runTasks(tasks map[string]Task) {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
+			defer close(finished[name])
+
+			// Wait for the tasks this task runs after. Tasks that do not run for the current target are ignored.
+			for _, after := range taskDependencies(name, tasks) {
+				if c, ok := finished[after]; ok {
+					<-c
+				}
+			}
 			runTask(name, task)
 		}()
 	}
@@ -204,15 +326,10 @@ func runTasks(tasks map[string]Task) {
 
 func runTask(name string, task Task) {
 	start := timestamp()
-	var files []string
-	if len(config.Targets) == 0 {
-		files = glob(task.Globs, config.SrcDir)
-	} else {
-		files = resolveTargetFiles(task.Globs)
-	}
+	files := resolveFiles(task.Globs)
 	prevOutput := files
 
-	if len(files) == 0 {
+	if len(files) == 0 && len(task.Globs) > 0 {
 		printFinishedTask(name, start)
 		return
 	}
@@ -221,6 +338,10 @@ func runTask(name string, task Task) {
 		if !shouldRunForTarget(config.Target, action.Targets) {
 			continue
 		}
+		// Tasks without globs only run the actions that work with the build directory until there are input files
+		if len(task.Globs) == 0 && len(prevOutput) == 0 && !stringInSlice(action.Action, buildDirActions) {
+			continue
+		}
 
 		var actioner Actioner
 		switch action.Action {
@@ -234,14 +355,57 @@ func runTask(name string, task Task) {
 			actioner = sassAction{}
 		case "shell":
 			actioner = shellAction{}
+		case "png-sprite":
+			actioner = pngSpriteAction{}
+		case "favicons":
+			actioner = faviconsAction{}
+		case "bundle":
+			actioner = bundleAction{}
+		case "css-bundle":
+			actioner = cssBundleAction{}
+		case "css-prefix":
+			actioner = cssPrefixAction{}
+		case "css-purge":
+			actioner = cssPurgeAction{}
+		case "markdown":
+			actioner = markdownAction{}
+		case "json-merge":
+			actioner = jsonMergeAction{}
+		case "i18n":
+			actioner = i18nAction{}
+		case "sitemap":
+			actioner = sitemapAction{}
+		case "precache-manifest":
+			actioner = precacheManifestAction{}
+		case "sri":
+			actioner = sriAction{}
+		case "html-inject":
+			actioner = htmlInjectAction{}
+		case "critical-css":
+			actioner = criticalCSSAction{}
+		case "inline":
+			actioner = inlineAction{}
 		default:
 			continue
 		}
-		prevOutput = actioner.Action(prevOutput, action.Options)
+		output := actioner.Action(prevOutput, action.Options)
+		recordActionOutputs(name, action.Action, prevOutput, output)
+		prevOutput = output
 	}
+
+	taskOutputsMutex.Lock()
+	taskOutputs[name] = prevOutput
+	taskOutputsMutex.Unlock()
 	printFinishedTask(name, start)
 }
 
+// outputsOfTask returns the output files of the last action of a task that has finished
+func outputsOfTask(name string) []string {
+	taskOutputsMutex.Lock()
+	defer taskOutputsMutex.Unlock()
+	return taskOutputs[name]
+}
+
 func shouldRunForTarget(currentTarget string, targets []string) bool {
 	if len(targets) == 0 {
 		return true
@@ -262,45 +426,11 @@ func printFinishedTask(name string, start int64) {
 	fmt.Printf("  %s: %s\n", fmtGreen(name), fmtCyan(timestamp()-start, "ms"))
 }
 
-func createZip(outputPath string) {
-	fmt.Printf("Creating archive...\n")
-	of, err := os.Create(outputPath)
-	if err != nil {
-		errorMsg(fmt.Sprintf("Could not create zip file '%s'", outputPath), err)
-		return
-	}
-	defer of.Close()
-
-	w := zip.NewWriter(of)
-	defer w.Close()
-
-	files, _, err := filesInPath(config.BuildDir)
-	if err != nil {
-		errorMsg(fmt.Sprintf("Folder '%s' not found.\n", config.BuildDir), err)
-		return
-	}
-
-	for _, file := range files {
-		data, err := ioutil.ReadFile(file)
-		if err != nil {
-			errorMsg(fmt.Sprintf("Could not read file '%s' while creating zip archive. Removing archive", file), err)
-			return
-		}
-
-		file = strings.Replace(file, config.BuildDir, "", -1)[1:]
-		f, err := w.Create(file)
-		if err != nil {
-			errorMsg(fmt.Sprintf("Could not add file '%s' to archive.", file), err)
-			return
-		}
-
-		_, err = f.Write(data)
-		if err != nil {
-			errorMsg(fmt.Sprintf("Could not write file '%s' to archive.", file), err)
-			return
-		}
+func resolveFiles(globs []string) []string {
+	if len(config.Targets) == 0 {
+		return glob(globs, config.SrcDir)
 	}
-	fmt.Printf("Created archive '%s'.\n\n", outputPath)
+	return resolveTargetFiles(globs)
 }
 
 func resolveTargetFiles(globs []string) []string {
@@ -328,18 +458,92 @@ func resolveTargetFiles(globs []string) []string {
 	return files
 }
 
+// resolveTargetPath finds the file that a source relative path resolves to, searching the current target first
+// and then each of its dependencies. The resolved file and the target that supplied it are returned.
+func resolveTargetPath(relative string) (file string, target string, ok bool) {
+	if len(config.Targets) == 0 {
+		file = fmt.Sprintf("%s%s", config.SrcDir, relative)
+		info, err := os.Stat(file)
+		return file, "", err == nil && !info.IsDir()
+	}
+
+	dependencies := getTargetDependencies(config.Target)
+	for i := len(dependencies) - 1; i >= 0; i-- {
+		file = fmt.Sprintf("%s/%s%s", config.SrcDir, dependencies[i], relative)
+		if info, err := os.Stat(file); err == nil && !info.IsDir() {
+			return file, dependencies[i], true
+		}
+	}
+	return "", "", false
+}
+
+// targetFileChain returns every file matching any of the source relative paths, ordered from the top-most
+// dependency to the current target
+func targetFileChain(relatives ...string) []string {
+	var files []string
+	bases := []string{config.SrcDir}
+	if len(config.Targets) > 0 {
+		bases = nil
+		for _, target := range getTargetDependencies(config.Target) {
+			bases = append(bases, fmt.Sprintf("%s/%s", config.SrcDir, target))
+		}
+	}
+
+	for _, base := range bases {
+		for _, relative := range relatives {
+			file := fmt.Sprintf("%s%s", base, relative)
+			if info, err := os.Stat(file); err == nil && !info.IsDir() {
+				files = append(files, file)
+			}
+		}
+	}
+	return files
+}
+
 func getTargetDependencies(target string) []string {
 	dependencies := []string{target}
 
 	for config.Targets[target].Dependency != "" {
 		target = config.Targets[target].Dependency
+		// Circular dependencies are rejected by setup, but must not loop forever for commands that report them
+		if stringInSlice(target, dependencies) {
+			break
+		}
 		dependencies = append([]string{target}, dependencies...)
 	}
 
 	return dependencies
 }
 
+// targetSetting looks up a setting on the current target, falling back to the settings of its dependencies
+func targetSetting(key string) (interface{}, bool) {
+	dependencies := getTargetDependencies(config.Target)
+	for i := len(dependencies) - 1; i >= 0; i-- {
+		if value, ok := config.Targets[dependencies[i]].Settings[key]; ok {
+			return value, true
+		}
+	}
+	return nil, false
+}
+
+// actionSetting returns a string action option, falling back to the target setting of the same name
+func actionSetting(options map[string]interface{}, key string, fallback string) string {
+	if value, ok := options[key].(string); ok {
+		return value
+	} else if value, ok := targetSetting(key); ok {
+		if value, ok := value.(string); ok {
+			return value
+		}
+	}
+	return fallback
+}
+
 func glob(globs []string, baseDir string) []string {
+	return globFiles(globs, baseDir, srcFiles)
+}
+
+// globFiles matches globs against a list of files relative to baseDir
+func globFiles(globs []string, baseDir string, files []string) []string {
 	var foundFiles []string
 	baseDirLen := len(baseDir)
 
@@ -367,7 +571,7 @@ func glob(globs []string, baseDir string) []string {
 				}
 			}
 		} else {
-			for _, file := range srcFiles {
+			for _, file := range files {
 				if len(file) <= baseDirLen || file[:baseDirLen] != baseDir {
 					continue
 				} else if r.MatchString(file[baseDirLen:]) {
@@ -397,6 +601,14 @@ func targetPathRegex() (*regexp.Regexp, error) {
 	return regexp.Compile(expression)
 }
 
+// relativePath strips the source target directory or build directory from a file path
+func relativePath(file string) string {
+	if regex, err := targetPathRegex(); err == nil {
+		file = regex.ReplaceAllString(file, "")
+	}
+	return strings.Replace(file, config.BuildDir, "", -1)
+}
+
 func checkValidTarget(targetName string, c Config) bool {
 	if targetName == "" && len(c.Targets) == 0 {
 		return true
@@ -417,11 +629,12 @@ func watch() {
 	}
 	defer watcher.Close()
 
-	file, _ := filepath.Abs(configFile)
-	err = watcher.Add(file)
-	if err != nil {
-		errorMsg(fmt.Sprintf("Could not add file '%s'", file), err)
-		os.Exit(1)
+	for _, file := range configFiles {
+		err = watcher.Add(file)
+		if err != nil {
+			errorMsg(fmt.Sprintf("Could not add file '%s'", file), err)
+			os.Exit(1)
+		}
 	}
 
 	watches := make(map[string]bool)